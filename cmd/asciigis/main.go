@@ -4,30 +4,96 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"asciigis/internal/geo"
+	"asciigis/internal/raster"
 	"asciigis/internal/tui"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [geojson path]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [path]\n       %s validate [-q] <path>...\n", os.Args[0], os.Args[0])
 		flag.PrintDefaults()
-		fmt.Fprintf(flag.CommandLine.Output(), "\nExamples:\n  %s /path/to/data.geojson\n  %s   # start then enter path interactively\n", os.Args[0], os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "\nExamples:\n  %s /path/to/data.geojson\n  %s   # start then enter path interactively\n  %s /path/to/parcels.shp   # or a .zip holding a shapefile\n  %s validate /path/to/data.geojson\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 	}
 
 	var mapWidth int
 	var mapHeight int
+	var directionGlyphs bool
+	var braille bool
+	var fill bool
+	var fillGlyphs string
+	var fillRuleName string
+	var elevation bool
+	var elevationGlyphs string
+	var cellAspect float64
+	var projection string
+	var srcCRSName string
+	var readoutCRSName string
 	flag.IntVar(&mapWidth, "W", 0, "Fixed canvas width (cells). 0 = auto")
 	flag.IntVar(&mapWidth, "width", 0, "Fixed canvas width (cells). 0 = auto")
 	flag.IntVar(&mapHeight, "H", 0, "Fixed canvas height (cells). 0 = auto")
 	flag.IntVar(&mapHeight, "height", 0, "Fixed canvas height (cells). 0 = auto")
+	flag.Float64Var(&cellAspect, "cell-aspect", geo.DefaultCellAspect, "Terminal cell height/width ratio used to keep the map's aspect ratio. Negative = stretch to fill")
+	flag.StringVar(&projection, "projection", geo.ProjectionNames[0], "Display projection: "+strings.Join(geo.ProjectionNames, ", "))
+	flag.StringVar(&srcCRSName, "src-crs", "", "CRS of the input coordinates, e.g. EPSG:6677, JGD2011:9, EPSG:32654, UTM:54N or \"+proj=tmerc ...\". Empty = the file's \"crs\" member, else WGS84 lon/lat")
+	flag.StringVar(&readoutCRSName, "readout-crs", "", "Projected CRS for cursor readouts (toggle with z). Empty = same as -src-crs")
+	flag.BoolVar(&directionGlyphs, "direction-glyphs", false, "Draw segments with direction-aware glyphs (- | / \\)")
+	flag.BoolVar(&braille, "braille", false, "Render with Unicode Braille characters (2x4 dots per cell)")
+	flag.BoolVar(&fill, "fill", false, "Shade polygon interiors")
+	flag.StringVar(&fillGlyphs, "fill-glyphs", string(raster.DefaultFillGlyphs), "Fill glyphs, assigned to features in turn")
+	flag.StringVar(&fillRuleName, "fill-rule", raster.EvenOdd.String(), "Fill rule: evenodd or nonzero")
+	flag.BoolVar(&elevation, "elevation", false, "Shade lines and points by their Z value (elevation)")
+	flag.StringVar(&elevationGlyphs, "elevation-glyphs", string(raster.DefaultElevationGlyphs), "Elevation glyphs, from lowest to highest")
 
 	flag.Parse()
+	if _, err := geo.NewProjection(projection, geo.Bound{}); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	fillRule, err := raster.ParseFillRule(fillRuleName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	// An empty -src-crs leaves the source CRS unset so the file's "crs" member applies.
+	var srcCRS geo.CRS
+	if srcCRSName != "" {
+		srcCRS, err = geo.ParseCRS(srcCRSName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -src-crs: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	readoutCRS, err := geo.ParseCRS(readoutCRSName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: -readout-crs: %v\n", err)
+		os.Exit(2)
+	}
 	geoPath := ""
 	if flag.NArg() >= 1 {
 		geoPath = flag.Arg(0)
 	}
-	if err := tui.RunWithOptions(geoPath, tui.Options{MapWidth: mapWidth, MapHeight: mapHeight}); err != nil {
+	if err := tui.RunWithOptions(geoPath, tui.Options{
+		MapWidth:        mapWidth,
+		MapHeight:       mapHeight,
+		DirectionGlyphs: directionGlyphs,
+		Braille:         braille,
+		Fill:            fill,
+		FillGlyphs:      []rune(fillGlyphs),
+		FillRule:        fillRule,
+		Elevation:       elevation,
+		ElevationGlyphs: []rune(elevationGlyphs),
+		Projection:      projection,
+		SourceCRS:       srcCRS,
+		ReadoutCRS:      readoutCRS,
+		CellAspect:      cellAspect,
+	}); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
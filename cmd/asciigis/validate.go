@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"asciigis/internal/geo"
+)
+
+// runValidate implements "asciigis validate": it parses each file, prints its
+// diagnostics and returns the process exit code (1 when any file has errors).
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	quiet := fs.Bool("q", false, "Print errors only (omit warnings and per-file summaries)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s validate [-q] <path>...\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExits with status 1 when any file has errors.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		diags, err := validateFile(path)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: error: %v\n", path, err)
+			code = 1
+		}
+		for _, diagnostic := range diags {
+			if *quiet && diagnostic.Severity != geo.SeverityError {
+				continue
+			}
+			fmt.Fprintf(stdout, "%s: %s\n", path, diagnostic)
+		}
+		if diags.HasErrors() {
+			code = 1
+		}
+		if !*quiet && err == nil {
+			fmt.Fprintf(stdout, "%s: %s\n", path, diags.Summary())
+		}
+	}
+	return code
+}
+
+// validateFile parses path and returns its diagnostics, including an
+// unsupported legacy "crs" member. err is set when the file cannot be loaded at all.
+func validateFile(path string) (geo.Diagnostics, error) {
+	layer, err := geo.ReadFile(path, nil)
+	diags := layer.Diagnostics
+	if err != nil {
+		return diags, err
+	}
+	if layer.CRSName != "" {
+		if _, err := geo.ParseCRS(layer.CRSName); err != nil {
+			diags = append(diags, geo.Diagnostic{
+				Severity: geo.SeverityWarning,
+				Pointer:  "/crs",
+				Message:  fmt.Sprintf("unsupported crs member: %v", err),
+			})
+		}
+	}
+	return diags, nil
+}
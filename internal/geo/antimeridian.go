@@ -0,0 +1,202 @@
+/*
+# antimeridian.go
+
+日付変更線（経度±180°）をまたぐデータを連続した経度で扱うモジュール
+*/
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// 隣り合う頂点の経度の差がこれを超えたら日付変更線をまたいだとみなす
+const antimeridianJump = 180.0
+
+/*
+WrapAntimeridian
+日付変更線をまたぐレイヤーを、経度が連続するようにずらして返す。
+
+ 1. パスの途中で経度が180°を超えて飛ぶ頂点を±360°ずらしてつなげる
+    （ずらすと閉じなくなるリング＝極を囲むリングはそのまま）
+ 2. パート（外周と穴）とラインの経度範囲の間で最も広い隙間が±180°以外にあれば、
+    その隙間より西のものを+360°ずらす。経度幅が180°以下に収まる場合だけ採用する
+
+ずらした座標は経度が180°を超えるが、表示（Transform）は経度をそのまま線形に扱うため
+フィジーのような範囲も1つながりで描画される。またがないレイヤーはそのまま返す。
+*/
+func WrapAntimeridian(layer Layer) Layer {
+	features, ok := wrapFeatures(layer.Features)
+	if !ok {
+		return layer
+	}
+	bound := calculateFeaturesBound(features)
+	layer.Features = features
+	layer.Bounds = *bound
+	return layer
+}
+
+// 経度が連続するようにずらしたフィーチャーを返す。ずらすものが無ければfalse
+func wrapFeatures(features []CachedFeature) ([]CachedFeature, bool) {
+	wrapped := make([]CachedFeature, len(features))
+	changed := false
+	var groups []lonGroup
+	for i, feature := range features {
+		out := feature
+		out.Parts = make([]CachedPart, len(feature.Parts))
+		for j, part := range feature.Parts {
+			rings := part.Rings()
+			for k, ring := range rings {
+				var unwrapped bool
+				rings[k], unwrapped = unwrapPath(ring, true)
+				changed = changed || unwrapped
+			}
+			// パートの位置は外周で決め、穴も同じだけずらす
+			shift := centerShift(rings[0])
+			for k, ring := range rings {
+				rings[k] = shiftPath(ring, shift)
+			}
+			changed = changed || shift != 0
+			out.Parts[j] = CachedPart{Exterior: rings[0], Holes: rings[1:], Measures: part.Measures}
+			groups = append(groups, newLonGroup(i, j, false, rings[0]))
+		}
+		out.Lines = make([][][2]float64, len(feature.Lines))
+		for j, line := range feature.Lines {
+			line, unwrapped := unwrapPath(line, false)
+			shift := centerShift(line)
+			out.Lines[j] = shiftPath(line, shift)
+			changed = changed || unwrapped || shift != 0
+			groups = append(groups, newLonGroup(i, j, true, out.Lines[j]))
+		}
+		wrapped[i] = out
+	}
+
+	// 最も広い隙間の西側を+360°ずらす
+	if west, ok := wrapWest(groups); ok {
+		for _, group := range groups {
+			if group.min >= west {
+				continue
+			}
+			feature := &wrapped[group.feature]
+			if group.line {
+				feature.Lines[group.index] = shiftPath(feature.Lines[group.index], 360)
+				continue
+			}
+			part := feature.Parts[group.index]
+			holes := make([][][2]float64, len(part.Holes))
+			for k, hole := range part.Holes {
+				holes[k] = shiftPath(hole, 360)
+			}
+			feature.Parts[group.index] = CachedPart{Exterior: shiftPath(part.Exterior, 360), Holes: holes, Measures: part.Measures}
+		}
+		changed = true
+	}
+	return wrapped, changed
+}
+
+/*
+隣り合う頂点の経度が180°を超えて飛ぶ箇所で、以降の頂点を±360°ずらしてつなげる。
+閉じたリングでずらした結果始点と終点が離れる場合（極を囲むリング）は元のパスを返す。
+ずらした頂点が無ければfalseと元のパスを返す（新しいスライスを作らない）。
+*/
+func unwrapPath(path [][2]float64, closed bool) ([][2]float64, bool) {
+	var out [][2]float64
+	offset := 0.0
+	for i := 1; i < len(path); i++ {
+		d := path[i][0] - path[i-1][0]
+		if d > antimeridianJump {
+			offset -= 360
+		} else if d < -antimeridianJump {
+			offset += 360
+		}
+		if offset != 0 && out == nil {
+			out = append(make([][2]float64, 0, len(path)), path[:i]...)
+		}
+		if out != nil {
+			out = append(out, [2]float64{path[i][0] + offset, path[i][1]})
+		}
+	}
+	if out == nil {
+		return path, false
+	}
+	if closed && math.Abs(out[len(out)-1][0]-out[0][0]) > antimeridianJump {
+		return path, false
+	}
+	return out, true
+}
+
+// パスの経度範囲の中心を [-180, 180] に戻すためのずらし量（360°の倍数）
+func centerShift(path [][2]float64) float64 {
+	lonMin, lonMax := lonRange(path)
+	return -360 * math.Round((lonMin+lonMax)/2/360)
+}
+
+// 経度をshiftだけずらしたパスを返す（shiftが0なら元のパス）
+func shiftPath(path [][2]float64, shift float64) [][2]float64 {
+	if shift == 0 {
+		return path
+	}
+	out := make([][2]float64, len(path))
+	for i, coord := range path {
+		out[i] = [2]float64{coord[0] + shift, coord[1]}
+	}
+	return out
+}
+
+func lonRange(path [][2]float64) (float64, float64) {
+	lonMin, lonMax := math.Inf(1), math.Inf(-1)
+	for _, coord := range path {
+		lonMin = math.Min(lonMin, coord[0])
+		lonMax = math.Max(lonMax, coord[0])
+	}
+	return lonMin, lonMax
+}
+
+// パートまたはラインの経度範囲
+type lonGroup struct {
+	feature, index int
+	line           bool
+	min, max       float64
+}
+
+func newLonGroup(feature, index int, line bool, path [][2]float64) lonGroup {
+	lonMin, lonMax := lonRange(path)
+	return lonGroup{feature: feature, index: index, line: line, min: lonMin, max: lonMax}
+}
+
+/*
+経度範囲の間で最も広い隙間が±180°側の隙間より広く、
+そこで切ると経度幅が180°以下に収まるなら、切った後の西端の経度を返す。
+*/
+func wrapWest(groups []lonGroup) (float64, bool) {
+	if len(groups) < 2 {
+		return 0, false
+	}
+	sorted := append([]lonGroup(nil), groups...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].min < sorted[j].min })
+
+	// 重なる範囲をまとめながら、範囲の間の最も広い隙間を探す
+	reach := sorted[0].max
+	gap, west, east := 0.0, 0.0, 0.0
+	for _, group := range sorted[1:] {
+		if group.min-reach > gap {
+			gap, west, east = group.min-reach, group.min, reach
+		}
+		reach = math.Max(reach, group.max)
+	}
+	wrapGap := sorted[0].min + 360 - reach
+	if gap <= wrapGap || east+360-west > 180 {
+		return 0, false
+	}
+	return west, true
+}
+
+// NormalizeLon は経度を [-180, 180) に戻す（WrapAntimeridian でずらした経度の表示用）
+func NormalizeLon(lon float64) float64 {
+	return lon - 360*math.Floor((lon+180)/360)
+}
+
+// CrossesAntimeridian は範囲が日付変更線をまたいでいればtrueを返す
+func (b Bound) CrossesAntimeridian() bool {
+	return b.LonMin < -180 || b.LonMax > 180
+}
@@ -0,0 +1,92 @@
+/*
+# charset.go
+
+UTF-8以外の文字コード（.cpg・XML宣言のencoding）をUTF-8に変換するモジュール
+*/
+package geo
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Windowsのコードページ番号（.cpg に書かれる "932" など）とWHATWGの名前の対応
+var codePageNames = map[int]string{
+	866:   "ibm866",
+	874:   "windows-874",
+	932:   "shift_jis",
+	936:   "gbk",
+	949:   "euc-kr",
+	950:   "big5",
+	20932: "euc-jp",
+	51932: "euc-jp",
+	54936: "gb18030",
+}
+
+/*
+lookupEncoding
+文字コードの名前を解釈する。UTF-8ならnilを返す。
+
+次の形を受け付ける（大文字・小文字は区別しない）:
+
+	"UTF-8" / "65001"
+	"Shift_JIS" / "SJIS" / "CP932" / "MS932" / "Windows-31J" / "932"
+	"1252" / "ANSI 1252" / "CP1252"（Windowsのコードページ番号）
+	"ISO-8859-1" / "8859-1" / "88591" / "Latin1"
+	その他WHATWG Encoding Standardの名前（"EUC-JP"・"GBK" など）
+
+ISO-8859-1 はWHATWGの扱い（Windows-1252と同一視）に従わず、0x80〜0x9Fも含めてそのまま変換する。
+*/
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	key = strings.TrimSpace(strings.TrimPrefix(key, "ansi"))
+	switch key {
+	case "utf-8", "utf8", "65001":
+		return nil, nil
+	case "sjis", "shift-jis", "cp932", "ms932", "windows-31j", "932":
+		return japanese.ShiftJIS, nil
+	case "iso-8859-1", "iso8859-1", "8859-1", "88591", "latin1", "28591":
+		return charmap.ISO8859_1, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(key, "cp")); err == nil {
+		switch {
+		case n >= 1250 && n <= 1258:
+			key = fmt.Sprintf("windows-%d", n)
+		case n >= 88592 && n <= 88599:
+			key = fmt.Sprintf("iso-8859-%d", n-88590)
+		case codePageNames[n] != "":
+			key = codePageNames[n]
+		}
+	}
+	enc, err := htmlindex.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+	return enc, nil
+}
+
+/*
+xmlCharsetReader
+encoding/xml の CharsetReader。XML宣言の encoding をUTF-8に変換する。
+扱えない文字コードは警告を残し、UTF-8として読む。
+*/
+func xmlCharsetReader(diags *Diagnostics) func(string, io.Reader) (io.Reader, error) {
+	return func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := lookupEncoding(charset)
+		if err != nil {
+			diags.warnf("", "%v; read as UTF-8", err)
+			return input, nil
+		}
+		if enc == nil {
+			return input, nil
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+}
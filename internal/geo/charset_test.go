@@ -0,0 +1,80 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"UTF-8", "東京", "東京"},
+		{"65001", "東京", "東京"},
+		{"Shift_JIS", "\x93\x8c\x8b\x9e", "東京"},
+		{"Windows-31J", "\x93\x8c\x8b\x9e", "東京"},
+		{"EUC-JP", "\xc5\xec\xb5\xfe", "東京"},
+		{"CP1252", "\x80", "€"},
+		{"ANSI 1251", "\xcc\xee\xf1\xea\xe2\xe0", "Москва"},
+		{"88592", "\xb3", "ł"},
+		// WHATWGと違い、0x80はWindows-1252の€にしない
+		{"ISO-8859-1", "\x80", "\u0080"},
+	}
+	for _, tt := range tests {
+		enc, err := lookupEncoding(tt.name)
+		if err != nil {
+			t.Errorf("lookupEncoding(%q): %v", tt.name, err)
+			continue
+		}
+		got := tt.raw
+		if enc != nil {
+			b, err := enc.NewDecoder().Bytes([]byte(tt.raw))
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			got = string(b)
+		}
+		if got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := lookupEncoding("x-unknown"); err == nil {
+		t.Error("lookupEncoding(x-unknown) succeeded, want error")
+	}
+}
+
+func TestReadKMLDeclaredEncoding(t *testing.T) {
+	kml := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>" +
+		"<kml><Placemark><name>Z\xfcrich</name><Point><coordinates>8.54,47.37</coordinates></Point></Placemark></kml>"
+	layer, err := ReadKML(strings.NewReader(kml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || layer.Features[0].Name != "Zürich" {
+		t.Errorf("features = %+v, want Zürich", layer.Features)
+	}
+
+	unknown := strings.NewReplacer("ISO-8859-1", "x-unknown", "Z\xfcrich", "Zurich").Replace(kml)
+	layer, err = ReadKML(strings.NewReader(unknown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasDiagnostic(layer.Diagnostics, SeverityWarning, "", "unsupported encoding") {
+		t.Errorf("diagnostics = %v, want the unsupported encoding", layer.Diagnostics)
+	}
+}
+
+func TestReadGPXDeclaredEncoding(t *testing.T) {
+	gpx := "<?xml version=\"1.0\" encoding=\"Shift_JIS\"?>" +
+		"<gpx><wpt lat=\"35.68\" lon=\"139.76\"><name>\x93\x8c\x8b\x9e</name></wpt></gpx>"
+	layer, err := ReadGPX(strings.NewReader(gpx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || layer.Features[0].Name != "東京" {
+		t.Errorf("features = %+v, want 東京", layer.Features)
+	}
+}
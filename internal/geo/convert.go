@@ -6,13 +6,14 @@
 package geo
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
-	"os"
 )
 
+// BytesToLayer はメモリ上のGeoJSONをLayer型に変換する（ReadLayer を参照）
 func BytesToLayer(data []byte) (Layer, error) {
 	// データが空の場合はエラーを返す
 	if len(data) == 0 {
@@ -20,89 +21,43 @@ func BytesToLayer(data []byte) (Layer, error) {
 			Valid: false,
 		}, errors.New("empty GeoJSON Bytes")
 	}
-
-	// jsonのパース
-	var geojson map[string]interface{}
-	if err := json.Unmarshal(data, &geojson); err != nil {
-		return Layer{
-			Valid: false,
-		}, fmt.Errorf("parse JSON: %w", err)
-	}
-
-	// Layer型に変換
-	layer, err := ConvertTuiLayer(geojson)
-	if err != nil {
-		return Layer{
-			Valid: false,
-		}, fmt.Errorf("convert to Layer: %w", err)
-	}
-
-	return layer, nil
+	return ReadLayer(bytes.NewReader(data), nil)
 }
 
 /*
 地理座標（経度緯度）をターミナルUI座標（X, Y）に変換する。
-【変換ロジック】
-X軸（東西）:
-- GeoJSONの経度: [lon_min, lon_max]
-- TUI座標: [0, width-1]
-- 線形補間で変換
-Y軸（南北）:
-- GeoJSONの緯度: [lat_min, lat_max]（北が大きい値）
-- TUI座標: [0, height-1]（上がY=0）
-- 上下反転が必要: y = height - 1 - normalized_y
+変換の詳細は NewTransform を参照。
 
 Args:
 
 	lon: 経度（例: 135.5）
 	lat: 緯度（例: 34.5）
-	bounds: 全体のBounding Box
-	width: ターミナル幅（セル数）
-	height: ターミナル高さ（セル数）
+	transform: 表示範囲とグリッドから作った変換
 
 Returns:
 
-	[x, y] のスライス。範囲は [0, width-1] × [0, height-1]
+	[x, y] のスライス。表示範囲内なら [0, width-1] × [0, height-1]。
+	範囲外の座標はキャンバス外の値になる（描画側でクリップする）
 */
-func geometoryToTui(lon, lat float64, bound *Bound, width, height int) [2]int {
-	// 経度の正規化
-	var xNorm, yNorm float64
-	if bound.lonSpan() > 0 {
-		xNorm = (lon - bound.LonMin) / bound.lonSpan() * float64(width-1)
-	} else {
-		xNorm = float64(width-1) / 2
-	}
-	x := int(math.Round(xNorm))
-
-	// 緯度の正規化（上下反転）
-	if bound.latSpan() > 0 {
-		yNorm = (bound.LatMax - lat) / bound.latSpan() * float64(height-1)
-	} else {
-		yNorm = float64(height-1) / 2
-	}
-	y := int(math.Round(yNorm))
+func geometoryToTui(lon, lat float64, transform *Transform) [2]int {
+	x, y := transform.Forward(lon, lat)
+	return [2]int{roundTuiCoord(x), roundTuiCoord(y)}
+}
 
-	if x < 0 {
-		x = 0
-	} else if x > width-1 {
-		x = width - 1
-	}
+// 拡大時に表示範囲外の座標がintに収まらなくなるのを防ぐための上限
+const maxTuiCoord = 1 << 24
 
-	if y < 0 {
-		y = 0
-	} else if y > height-1 {
-		y = height - 1
-	}
-
-	return [2]int{x, y}
+// TUI座標を丸め、±maxTuiCoordに収める
+func roundTuiCoord(v float64) int {
+	return int(math.Round(math.Max(-maxTuiCoord, math.Min(maxTuiCoord, v))))
 }
 
 /*
 ConvertTui
-GeoJSONファイルを読み込み、地理座標をターミナルUI座標に変換する。
+GeoJSONファイル（またはシェープファイル、ReadFile を参照）を読み込み、地理座標をターミナルUI座標に変換する。
 Args:
 
-	path: GeoJSONファイルのパス
+	path: ファイルのパス
 	width: ターミナル幅（セル数）
 	height: ターミナル高さ（セル数）
 
@@ -112,19 +67,14 @@ Returns:
 */
 
 func ConvertTui(path string, width, height int) (TuiGeometry, error) {
-	// geojsonファイルの読み込み
-	data, err := os.ReadFile(path)
+	// 拡張子に応じた形式で読み込む（GeoJSONはストリームで読む）
+	layer, err := ReadFile(path, nil)
 	if err != nil {
-		return TuiGeometry{}, fmt.Errorf("read file: %w", err)
+		return TuiGeometry{}, err
 	}
+	layer = WrapAntimeridian(layer)
 
-	// jsonのパース
-	layer, err := BytesToLayer(data)
-	if err != nil {
-		return TuiGeometry{}, fmt.Errorf("parse JSON: %w", err)
-	}
-
-	return ConvertTuiBytes(layer, width, height)
+	return ConvertTuiBytes(layer, layer.Bounds, nil, width, height, DefaultCellAspect)
 }
 
 /*
@@ -134,48 +84,79 @@ ConvertTuiBytes
 Args:
 
 	data: パース済みのGeoJSONデータ
+	view: 表示範囲（全体を表示する場合は layer.Bounds）
+	proj: 表示用の投影法（nilなら正距円筒図法）。範囲と拡大率は投影座標で計算する
 	width: ターミナル幅（セル数）
 	height: ターミナル高さ（セル数）
+	aspect: セルの縦横比（高さ/幅）。0以下なら縦横比を保たず引き伸ばす
 
 Returns:
 
 	TuiGeometry
 */
-func ConvertTuiBytes(layer Layer, width, height int) (TuiGeometry, error) {
+func ConvertTuiBytes(layer Layer, view Bound, proj Projection, width, height int, aspect float64) (TuiGeometry, error) {
+	transform := NewTransform(view, proj, width, height, aspect)
+
 	// 各featureの処理
 	var polygons []Polygon
 	for _, feature := range layer.Features {
-		// 各ringsの処理
-		rings := feature.Rings
-		var tuiRings [][][2]int
-		for _, ring := range rings {
-			var tuiRing [][2]int
-			for _, coord := range ring {
-				lon, lat := coord[0], coord[1]
-				tuiCoord := geometoryToTui(lon, lat, &layer.Bounds, width, height)
-				tuiRing = append(tuiRing, tuiCoord)
-			}
-			tuiRings = append(tuiRings, tuiRing)
-		}
-
 		polygon := Polygon{
-			Name:       feature.Name,
-			Properties: feature.Properties,
-			Rings:      tuiRings,
+			Name:         feature.Name,
+			Properties:   feature.Properties,
+			Parts:        partsToTui(feature.Parts, &transform),
+			Lines:        pathsToTui(feature.Lines, &transform),
+			LineMeasures: feature.LineMeasures,
 		}
 		polygons = append(polygons, polygon)
 	}
 
 	return TuiGeometry{
-		Bounds:   layer.Bounds,
-		Width:    width,
-		Height:   height,
-		Polygons: polygons,
+		Bounds:    view,
+		Width:     width,
+		Height:    height,
+		Polygons:  polygons,
+		Transform: transform,
+		Elevation: layer.Elevation,
 	}, nil
 }
 
+// 経度緯度座標系のポリゴンパートをTUI座標系に変換する
+func partsToTui(parts []CachedPart, transform *Transform) []PolygonPart {
+	var tuiParts []PolygonPart
+	for _, part := range parts {
+		tuiParts = append(tuiParts, PolygonPart{
+			Exterior: pathToTui(part.Exterior, transform),
+			Holes:    pathsToTui(part.Holes, transform),
+			Measures: part.Measures,
+		})
+	}
+	return tuiParts
+}
+
+// 経度緯度座標系のリング/ラインの集合をTUI座標系に変換する
+func pathsToTui(paths [][][2]float64, transform *Transform) [][][2]int {
+	var tuiPaths [][][2]int
+	for _, path := range paths {
+		tuiPaths = append(tuiPaths, pathToTui(path, transform))
+	}
+	return tuiPaths
+}
+
+// 経度緯度座標系のリング/ラインをTUI座標系に変換する
+func pathToTui(path [][2]float64, transform *Transform) [][2]int {
+	var tuiPath [][2]int
+	for _, coord := range path {
+		lon, lat := coord[0], coord[1]
+		tuiCoord := geometoryToTui(lon, lat, transform)
+		tuiPath = append(tuiPath, tuiCoord)
+	}
+	return tuiPath
+}
+
 // ConvertTuiLayer
 // パース済みのgeojsonデータを読み込み、地理座標をLayer型で返す。
+// featuresは1つずつ Feature にデコードしてLayerに追加するため、文書全体をJSONに戻すことはない。
+// ルートの扱いや診断は ReadLayer と同じになる。
 //
 // Args:
 //
@@ -192,85 +173,184 @@ func ConvertTuiLayer(geojson map[string]interface{}) (Layer, error) {
 		}, errors.New("geojson is nil")
 	}
 
-	// featuresの取得
-	featuresInterface, ok := geojson["features"]
+	builder := newLayerBuilder()
+	root := make(map[string]json.RawMessage, len(geojson))
+	streamed := false
+	for key, value := range geojson {
+		if key == "features" {
+			features, ok := value.([]interface{})
+			if !ok {
+				return Layer{Valid: false}, errors.New("features is not a slice")
+			}
+			if err := builder.convertFeatures(features); err != nil {
+				return Layer{Valid: false}, err
+			}
+			streamed = true
+			continue
+		}
+		// features以外のメンバー（type・crs・bboxなど）は小さいのでJSONに戻して ReadLayer と同じように扱う
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return Layer{Valid: false}, fmt.Errorf("encode GeoJSON member %q: %w", key, err)
+		}
+		root[key] = raw
+	}
+	return builder.finishRoot(root, streamed)
+}
+
+// パース済みのfeaturesを1要素ずつ Feature にしてLayerに追加する
+func (b *layerBuilder) convertFeatures(features []interface{}) error {
+	for i, value := range features {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("encode feature %d: %w", i, err)
+		}
+		var feature Feature
+		if err := json.Unmarshal(raw, &feature); err != nil {
+			return fmt.Errorf("parse JSON: %w", err)
+		}
+		b.addFeature(feature, pointerIndex("/features", i))
+	}
+	return nil
+}
+
+// フィーチャーを1つずつ受け取り、境界ボックスと診断を積み上げながらLayerを組み立てる
+type layerBuilder struct {
+	features []CachedFeature
+	bound    Bound
+	diags    Diagnostics
+	skipped  int
+	// 全頂点の高さの範囲
+	elevation Range
+}
+
+func newLayerBuilder() *layerBuilder {
+	return &layerBuilder{bound: emptyBound(), elevation: emptyRange()}
+}
+
+// フィーチャーを1つ追加する。pointer はフィーチャーの位置（診断用のJSON Pointer）。
+// geometryが無い・null・座標を取り出せないフィーチャーは読み飛ばして数える
+func (b *layerBuilder) addFeature(feature Feature, pointer string) {
+	b.diags.addAll(pointer, feature.Diagnostics)
+	if !feature.Valid || feature.Geometry == nil {
+		b.skipped++
+		return
+	}
+	parts, lines, lineMeasures := feature.Geometry.Flatten()
+	if len(parts) == 0 && len(lines) == 0 {
+		b.diags.errorf(pointer, "no valid coordinates; feature skipped")
+		b.skipped++
+		return
+	}
+
+	name, ok := feature.Properties["name"]
 	if !ok {
-		return Layer{
-			Valid: false,
-		}, errors.New("features not found in GeoJSON")
+		name = "unknown"
 	}
-	// 型アサーション
-	features, ok := featuresInterface.([]interface{})
+
+	nameString, ok := name.(string)
 	if !ok {
-		return Layer{
-			Valid: false,
-		}, errors.New("features is not a slice")
+		nameString = "unknown"
 	}
-	// featuresをmap[string]interface{}のスライスに変換
-	var featureMaps []map[string]interface{}
-	for _, feature := range features {
-		featureMap, ok := feature.(map[string]interface{})
-		if !ok {
-			continue
+
+	cached := CachedFeature{
+		Name:         nameString,
+		Properties:   feature.Properties,
+		Parts:        parts,
+		Lines:        lines,
+		LineMeasures: lineMeasures,
+	}
+	b.features = append(b.features, cached)
+	// 境界ボックスはデコード時に計算済みのものを合わせる
+	b.bound = b.bound.union(feature.Geometry.Bounds)
+	for _, part := range parts {
+		for _, measures := range part.Measures {
+			b.elevation.extend(measures.Z)
 		}
-		featureMaps = append(featureMaps, featureMap)
 	}
-
-	// バウンディングボックスの計算
-	bound := calculateBoundingBox(featureMaps)
-	if math.IsInf(bound.LonMin, 0) || math.IsInf(bound.LatMin, 0) {
-		return Layer{
-			Valid: false,
-		}, errors.New("bounding box could not be calculated")
+	for _, measures := range lineMeasures {
+		b.elevation.extend(measures.Z)
 	}
 
-	// 各featureの処理
-	var layerFeatures []CachedFeature
-	for _, feature := range featureMaps {
-		// geometryの取得
-		// 型アサーションしてmap[string]interface{}に変換
-		geometryField, ok := feature["geometry"]
-		if !ok {
-			continue
+	// フィーチャーのbboxはジオメトリと照合するだけ（表示範囲には使わない）
+	if feature.BBox != nil {
+		computed := feature.Geometry.Bounds
+		if feature.BBox.CrossesAntimeridian() {
+			if wrapped, ok := wrapFeatures([]CachedFeature{cached}); ok {
+				computed = *calculateFeaturesBound(wrapped)
+			}
 		}
-		geometry, ok := geometryField.(map[string]interface{})
-		if !ok {
-			continue
+		if boundsDisagree(*feature.BBox, computed) {
+			b.diags.warnf(pointerKey(pointer, "bbox"), "bbox %s disagrees with the geometry %s", formatBBox(*feature.BBox), formatBBox(computed))
 		}
+	}
+}
 
-		// propertiesの取得
-		propertiesField, ok := feature["properties"]
-		if !ok {
-			continue
-		}
-		properties, ok := propertiesField.(map[string]interface{})
-		if !ok {
-			continue
-		}
+// ジオメトリを1つ追加する。pointer はジオメトリの位置。
+// GeometryCollection はメンバーごとに（入れ子も再帰的に展開して）別のフィーチャーにする
+func (b *layerBuilder) addGeometry(geometry Geometry, pointer string) {
+	if geometry.Type != GeometryGeometryCollection {
+		b.addFeature(geometryFeature(geometry), pointer)
+		return
+	}
+	b.diags.addAll(pointer, geometry.Diagnostics)
+	for i, member := range geometry.Geometries {
+		b.addGeometry(member, pointerIndex(pointerKey(pointer, "geometries"), i))
+	}
+}
 
-		rings := extractCoordinates(geometry)
+// ルートのメンバー（crs・bbox）を反映してLayerを返す
+func (b *layerBuilder) finish(root map[string]json.RawMessage) (Layer, error) {
+	bound := b.bound
+	if math.IsInf(bound.LonMin, 0) || math.IsInf(bound.LatMin, 0) {
+		return Layer{
+			Valid:       false,
+			Diagnostics: b.diags,
+		}, errors.New("bounding box could not be calculated")
+	}
 
-		name, ok := properties["name"]
-		if !ok {
-			name = "unknown"
+	// 宣言されたbboxは初期表示範囲に使い、計算した境界ボックスと食い違えば警告する
+	var declaredBounds *Bound
+	if raw, ok := root["bbox"]; ok {
+		var bboxField interface{}
+		_ = json.Unmarshal(raw, &bboxField)
+		declared, err := parseBBox(bboxField)
+		// 日付変更線をまたぐbboxは、経度をつなげた後の範囲と比べる（WrapAntimeridian を参照）
+		computed := bound
+		if err == nil && declared.CrossesAntimeridian() {
+			if wrapped, ok := wrapFeatures(b.features); ok {
+				computed = *calculateFeaturesBound(wrapped)
+			}
 		}
-
-		nameString, ok := name.(string)
-		if !ok {
-			nameString = "unknown"
+		switch {
+		case err != nil:
+			b.diags.warnf("/bbox", "ignoring bbox: %v", err)
+		case boundsDisagree(*declared, computed):
+			b.diags.warnf("/bbox", "declared bbox %s disagrees with computed bounds %s", formatBBox(*declared), formatBBox(computed))
+			declaredBounds = declared
+		default:
+			declaredBounds = declared
 		}
+	}
 
-		cachedFeature := CachedFeature{
-			Name:       nameString,
-			Properties: properties,
-			Rings:      rings,
-		}
-		layerFeatures = append(layerFeatures, cachedFeature)
+	var crsField interface{}
+	if raw, ok := root["crs"]; ok {
+		_ = json.Unmarshal(raw, &crsField)
+	}
+
+	var elevation *Range
+	if b.elevation.valid() {
+		elevation = &b.elevation
 	}
 
 	return Layer{
-		Bounds:   *bound,
-		Features: layerFeatures,
-		Valid:    true,
+		Bounds:         bound,
+		Features:       b.features,
+		Valid:          true,
+		CRSName:        parseCRSMember(crsField),
+		DeclaredBounds: declaredBounds,
+		Skipped:        b.skipped,
+		Diagnostics:    b.diags,
+		Elevation:      elevation,
 	}, nil
 }
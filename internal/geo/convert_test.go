@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestConvertTuiLayerMatchesReadLayer(t *testing.T) {
+	for _, text := range []string{
+		`{"type":"FeatureCollection","bbox":[0,0,10,10],"features":[
+			{"type":"Feature","properties":{"name":"a","n":1},"geometry":{"type":"Point","coordinates":[1,2,3]}},
+			{"type":"Feature","properties":{},"geometry":null},
+			{"type":"Feature","properties":{"name":"b"},"geometry":{"type":"LineString","coordinates":[[0,0],[1,"x"],[2,2]]}}
+		]}`,
+		`{"type":"Feature","properties":{"name":"root"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}`,
+		`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"Point","coordinates":[3,4]}]}`,
+	} {
+		want, err := ReadLayer(strings.NewReader(text), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var geojson map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &geojson); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ConvertTuiLayer(geojson)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 診断の順はルートのメンバーの順によらないよう並べて比べる
+		for _, layer := range []*Layer{&want, &got} {
+			sort.Slice(layer.Diagnostics, func(i, j int) bool {
+				return layer.Diagnostics[i].Pointer < layer.Diagnostics[j].Pointer
+			})
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ConvertTuiLayer = %+v\nReadLayer = %+v", got, want)
+		}
+	}
+}
+
+func TestConvertTuiLayerErrors(t *testing.T) {
+	for _, geojson := range []map[string]interface{}{
+		nil,
+		{},
+		{"type": "FeatureCollection", "features": "x"},
+		{"type": "Circle"},
+	} {
+		if _, err := ConvertTuiLayer(geojson); err == nil {
+			t.Errorf("ConvertTuiLayer(%v) succeeded, want error", geojson)
+		}
+	}
+}
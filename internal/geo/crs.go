@@ -0,0 +1,202 @@
+/*
+# crs.go
+
+入力データの座標参照系（CRS）と、経度緯度への再投影
+*/
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CRS は入力データの座標参照系
+// JGD2011とWGS84の差は表示上無視できるため、どちらも経度緯度として同一視する
+type CRS struct {
+	// 表示用の名前（例: "EPSG:6677 (JGD2011 / IX)"）
+	Name string
+	// 平面座標系の投影法。nilなら経度緯度
+	Projection Projection
+}
+
+// WGS84の経度緯度
+var CRSWGS84 = CRS{Name: "WGS84"}
+
+// IsGeographic は経度緯度の座標系ならtrue
+func (c CRS) IsGeographic() bool {
+	return c.Projection == nil
+}
+
+/*
+ParseCRS
+CRSの名前を解釈する。大文字小文字は区別しない。
+
+対応する名前:
+
+	"" / "WGS84" / "CRS84" / "EPSG:4326" / "EPSG:6668"（JGD2011経度緯度）: 経度緯度
+	"EPSG:6669" 〜 "EPSG:6687": JGD2011 平面直角座標系 I〜XIX系
+	"JGD2011:9" / "JGD2011:IX": JGD2011 平面直角座標系（系番号またはローマ数字）
+	"EPSG:32601" 〜 "EPSG:32660" / "EPSG:32701" 〜 "EPSG:32760": WGS84 UTM 北半球 / 南半球
+	"UTM:54N" / "UTM:54S": WGS84 UTM（半球の省略は北半球）
+	"+proj=tmerc ..." / "+proj=utm ...": PROJ形式の横メルカトル図法（parseProjString を参照）
+
+GeoJSONのcrsメンバーで使われるURN（"urn:ogc:def:crs:EPSG::6677"、
+"urn:ogc:def:crs:OGC:1.3:CRS84"）とURL（"http://www.opengis.net/def/crs/EPSG/0/6677"）も受け付ける。
+*/
+func ParseCRS(name string) (CRS, error) {
+	if strings.HasPrefix(strings.TrimSpace(name), "+") {
+		return parseProjString(name)
+	}
+	key := normalizeCRSName(strings.ToUpper(strings.TrimSpace(name)))
+	switch key {
+	case "", "WGS84", "CRS84", "OGC:CRS84", "EPSG:4326":
+		return CRSWGS84, nil
+	case "EPSG:6668", "JGD2011":
+		return CRS{Name: "JGD2011"}, nil
+	}
+
+	if code, ok := strings.CutPrefix(key, "EPSG:"); ok {
+		n, err := strconv.Atoi(code)
+		if err != nil {
+			return CRS{}, fmt.Errorf("invalid EPSG code: %q", name)
+		}
+		if zone := n - jgd2011ZoneBaseEPSG + 1; zone >= 1 && zone <= len(jgd2011Origins) {
+			return jgd2011CRS(zone)
+		}
+		if zone := n - utmNorthBaseEPSG + 1; zone >= 1 && zone <= utmZones {
+			return utmCRS(zone, false)
+		}
+		if zone := n - utmSouthBaseEPSG + 1; zone >= 1 && zone <= utmZones {
+			return utmCRS(zone, true)
+		}
+		return CRS{}, fmt.Errorf("unsupported CRS: %q", name)
+	}
+	if zoneName, ok := strings.CutPrefix(key, "UTM:"); ok {
+		zone, south, err := parseUTMZone(zoneName)
+		if err != nil {
+			return CRS{}, err
+		}
+		return utmCRS(zone, south)
+	}
+	if zoneName, ok := strings.CutPrefix(key, "JGD2011:"); ok {
+		zone, err := parseJGD2011Zone(zoneName)
+		if err != nil {
+			return CRS{}, err
+		}
+		return jgd2011CRS(zone)
+	}
+	return CRS{}, fmt.Errorf("unsupported CRS: %q", name)
+}
+
+// URN / URL形式のCRS名を "EPSG:6677" / "CRS84" の形にそろえる（大文字化済みの名前を受け取る）
+func normalizeCRSName(key string) string {
+	for _, prefix := range []string{"URN:OGC:DEF:CRS:", "HTTP://WWW.OPENGIS.NET/DEF/CRS/", "HTTPS://WWW.OPENGIS.NET/DEF/CRS/"} {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		// "EPSG::6677" / "EPSG:9.8:6677" / "EPSG/0/6677" → 機関名と最後の要素
+		fields := strings.FieldsFunc(rest, func(r rune) bool { return r == ':' || r == '/' })
+		if len(fields) < 2 {
+			return key
+		}
+		authority, code := fields[0], fields[len(fields)-1]
+		if authority == "OGC" {
+			return code
+		}
+		return authority + ":" + code
+	}
+	return key
+}
+
+func jgd2011CRS(zone int) (CRS, error) {
+	proj, err := JGD2011Zone(zone)
+	if err != nil {
+		return CRS{}, err
+	}
+	return CRS{
+		Name:       fmt.Sprintf("EPSG:%d (JGD2011 / %s)", jgd2011ZoneBaseEPSG+zone-1, JGD2011ZoneName(zone)),
+		Projection: proj,
+	}, nil
+}
+
+// 系番号（"9"）またはローマ数字（"IX"）を解釈する
+func parseJGD2011Zone(value string) (int, error) {
+	if zone, err := strconv.Atoi(value); err == nil {
+		return zone, nil
+	}
+	for i, roman := range romanNumerals {
+		if roman == value {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid JGD2011 zone: %q", value)
+}
+
+// ReprojectLayer はcrsの平面座標で書かれたレイヤーを経度緯度に変換し、境界ボックスを計算し直す
+// crsが経度緯度ならそのまま返す
+func ReprojectLayer(layer Layer, crs CRS) (Layer, error) {
+	if crs.IsGeographic() {
+		return layer, nil
+	}
+	toLonLat := func(path [][2]float64) [][2]float64 {
+		out := make([][2]float64, len(path))
+		for i, coord := range path {
+			lon, lat := crs.Projection.Inverse(coord[0], coord[1])
+			out[i] = [2]float64{lon, lat}
+		}
+		return out
+	}
+
+	features := make([]CachedFeature, len(layer.Features))
+	for i, feature := range layer.Features {
+		reprojected := feature
+		reprojected.Parts = make([]CachedPart, len(feature.Parts))
+		for j, part := range feature.Parts {
+			holes := make([][][2]float64, len(part.Holes))
+			for k, hole := range part.Holes {
+				holes[k] = toLonLat(hole)
+			}
+			reprojected.Parts[j] = CachedPart{Exterior: toLonLat(part.Exterior), Holes: holes, Measures: part.Measures}
+		}
+		reprojected.Lines = make([][][2]float64, len(feature.Lines))
+		for j, line := range feature.Lines {
+			reprojected.Lines[j] = toLonLat(line)
+		}
+		features[i] = reprojected
+	}
+
+	if layer.DeclaredBounds != nil {
+		declared := reprojectBound(*layer.DeclaredBounds, crs.Projection)
+		layer.DeclaredBounds = &declared
+	}
+
+	bound := calculateFeaturesBound(features)
+	if math.IsInf(bound.LonMin, 0) || math.IsInf(bound.LatMin, 0) || math.IsNaN(bound.LonMin) || math.IsNaN(bound.LatMin) {
+		return Layer{Valid: false}, errors.New("bounding box could not be calculated after reprojection")
+	}
+	layer.Features = features
+	layer.Bounds = *bound
+	return layer, nil
+}
+
+// 平面座標の範囲を経度緯度に変換する（辺上の点を逆投影した範囲）
+func reprojectBound(b Bound, proj Projection) Bound {
+	out := Bound{LonMin: math.Inf(1), LonMax: math.Inf(-1), LatMin: math.Inf(1), LatMax: math.Inf(-1)}
+	for i := 0; i <= boundSamples; i++ {
+		t := float64(i) / boundSamples
+		x := b.LonMin + (b.LonMax-b.LonMin)*t
+		y := b.LatMin + (b.LatMax-b.LatMin)*t
+		for _, p := range [][2]float64{{x, b.LatMin}, {x, b.LatMax}, {b.LonMin, y}, {b.LonMax, y}} {
+			lon, lat := proj.Inverse(p[0], p[1])
+			out.LonMin = math.Min(out.LonMin, lon)
+			out.LonMax = math.Max(out.LonMax, lon)
+			out.LatMin = math.Min(out.LatMin, lat)
+			out.LatMax = math.Max(out.LatMax, lat)
+		}
+	}
+	return out
+}
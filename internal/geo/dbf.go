@@ -0,0 +1,196 @@
+/*
+# dbf.go
+
+シェープファイルの属性（dBASE .dbf）を読み込むモジュール
+*/
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+const (
+	// .dbf のヘッダーとフィールド定義1つの長さ（バイト）
+	dbfHeaderSize = 32
+	dbfFieldSize  = 32
+	// フィールド定義の終わり
+	dbfHeaderTerminator = 0x0D
+	// 削除済みレコードの印
+	dbfDeleted = '*'
+)
+
+// .dbf のフィールド定義
+type dbfField struct {
+	name     string
+	kind     byte
+	length   int
+	decimals int
+}
+
+// .dbf の中身。records はレコードごとのプロパティ（削除済みのレコードも位置を保つ）
+type dbfTable struct {
+	fields  []dbfField
+	records []map[string]interface{}
+}
+
+/*
+readDBF
+.dbf を読み、レコードごとのプロパティを返す。dbfがnilならnilを返す。
+
+値の型:
+
+	C（文字）: 前後の空白を除いた文字列
+	N・F（数値）: float64（空欄はnil）
+	L（論理）: bool（"?" や空欄はnil）
+	D（日付）: "2024-05-01" 形式の文字列
+	その他: 前後の空白を除いた文字列
+
+文字列は .cpg の文字コード（Shift_JIS・Windows-1252 など）で読む。.cpg が無ければ、
+内容がUTF-8として正しければUTF-8、ヘッダーの言語ドライバIDが分かればその文字コード、
+それ以外はLatin-1として読んで警告を記録する（dbfDecoder を参照）。
+*/
+func readDBF(dbf, cpg []byte, diags *Diagnostics) (*dbfTable, error) {
+	if dbf == nil {
+		return nil, nil
+	}
+	if len(dbf) < dbfHeaderSize {
+		return nil, errors.New(".dbf header is truncated")
+	}
+	numRecords := int(binary.LittleEndian.Uint32(dbf[4:8]))
+	headerSize := int(binary.LittleEndian.Uint16(dbf[8:10]))
+	recordSize := int(binary.LittleEndian.Uint16(dbf[10:12]))
+	if headerSize > len(dbf) || recordSize < 1 {
+		return nil, errors.New(".dbf header is invalid")
+	}
+
+	table := &dbfTable{}
+	offset := 1 // 削除フラグ
+	for pos := dbfHeaderSize; pos+dbfFieldSize <= headerSize && dbf[pos] != dbfHeaderTerminator; pos += dbfFieldSize {
+		descriptor := dbf[pos : pos+dbfFieldSize]
+		name := descriptor[:11]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		field := dbfField{
+			name:     strings.TrimSpace(string(name)),
+			kind:     descriptor[11],
+			length:   int(descriptor[16]),
+			decimals: int(descriptor[17]),
+		}
+		table.fields = append(table.fields, field)
+		offset += field.length
+	}
+	if offset > recordSize {
+		return nil, fmt.Errorf("fields need %d bytes but records have %d", offset, recordSize)
+	}
+
+	decode := dbfDecoder(dbf[29], dbf[headerSize:], cpg, diags)
+	for i := 0; i < numRecords; i++ {
+		start := headerSize + i*recordSize
+		if start+recordSize > len(dbf) {
+			diags.errorf(pointerIndex("/dbf/records", i), "attribute record is truncated; %d of %d records read", i, numRecords)
+			break
+		}
+		record := dbf[start : start+recordSize]
+		properties := map[string]interface{}{}
+		if record[0] != dbfDeleted {
+			pos := 1
+			for _, field := range table.fields {
+				properties[field.name] = dbfValue(field, record[pos:pos+field.length], decode)
+				pos += field.length
+			}
+		}
+		table.records = append(table.records, properties)
+	}
+	return table, nil
+}
+
+// .dbf のヘッダーの言語ドライバID（.cpg が無いときに使う）と文字コード
+var dbfLanguageDrivers = map[byte]encoding.Encoding{
+	0x01: charmap.CodePage437,
+	0x02: charmap.CodePage850,
+	0x03: charmap.Windows1252,
+	0x13: japanese.ShiftJIS,
+	0x57: charmap.Windows1252,
+}
+
+/*
+文字列の復号方法を決める。
+ 1. .cpg があればその文字コード（lookupEncoding）。対応していなければエラーを記録してLatin-1
+ 2. 内容がUTF-8として正しければUTF-8
+ 3. ヘッダーの言語ドライバIDが分かればその文字コード（0x13 ならShift_JIS）
+ 4. それ以外はLatin-1として読み、警告を記録する
+*/
+func dbfDecoder(languageDriver byte, records, cpg []byte, diags *Diagnostics) func([]byte) string {
+	var enc encoding.Encoding
+	codepage := strings.TrimSpace(string(cpg))
+	switch {
+	case codepage != "":
+		var err error
+		if enc, err = lookupEncoding(codepage); err != nil {
+			diags.errorf("/cpg", "%v; attribute text read as Latin-1", err)
+			enc = charmap.ISO8859_1
+		}
+	case utf8.Valid(records):
+	case dbfLanguageDrivers[languageDriver] != nil:
+		enc = dbfLanguageDrivers[languageDriver]
+	default:
+		diags.warnf("/dbf", "attribute text is not UTF-8 and no .cpg was given; read as Latin-1")
+		enc = charmap.ISO8859_1
+	}
+	if enc == nil {
+		return func(b []byte) string { return string(b) }
+	}
+	decoder := enc.NewDecoder()
+	return func(b []byte) string {
+		text, err := decoder.Bytes(b)
+		if err != nil {
+			return string(b)
+		}
+		return string(text)
+	}
+}
+
+// フィールドの値を型に応じて変換する
+func dbfValue(field dbfField, raw []byte, decode func([]byte) string) interface{} {
+	switch field.kind {
+	case 'N', 'F':
+		text := strings.TrimSpace(string(raw))
+		if text == "" || strings.Trim(text, "*") == "" {
+			return nil
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return text
+		}
+		return v
+	case 'L':
+		switch strings.ToUpper(strings.TrimSpace(string(raw))) {
+		case "T", "Y":
+			return true
+		case "F", "N":
+			return false
+		}
+		return nil
+	case 'D':
+		text := strings.TrimSpace(string(raw))
+		if len(text) == 8 {
+			return text[0:4] + "-" + text[4:6] + "-" + text[6:8]
+		}
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+	return strings.TrimSpace(decode(bytes.TrimRight(raw, "\x00")))
+}
@@ -0,0 +1,113 @@
+package geo
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// 文字フィールド "NAME"（長さ20）を1つ持つ .dbf を組み立てる
+func nameDBF(languageDriver byte, names ...[]byte) []byte {
+	const length = 20
+	headerSize := dbfHeaderSize + dbfFieldSize + 1
+	dbf := make([]byte, headerSize)
+	dbf[0] = 0x03
+	binary.LittleEndian.PutUint32(dbf[4:], uint32(len(names)))
+	binary.LittleEndian.PutUint16(dbf[8:], uint16(headerSize))
+	binary.LittleEndian.PutUint16(dbf[10:], uint16(1+length))
+	dbf[29] = languageDriver
+	field := dbf[dbfHeaderSize:]
+	copy(field, "NAME")
+	field[11] = 'C'
+	field[16] = length
+	dbf[headerSize-1] = dbfHeaderTerminator
+	for _, name := range names {
+		record := make([]byte, 1+length)
+		for i := range record {
+			record[i] = ' '
+		}
+		copy(record[1:], name)
+		dbf = append(dbf, record...)
+	}
+	return dbf
+}
+
+func TestReadDBFCodePages(t *testing.T) {
+	tests := []struct {
+		name           string
+		cpg            string
+		languageDriver byte
+		raw            []byte
+		want           string
+	}{
+		{"UTF-8 cpg", "UTF-8", 0, []byte("東京"), "東京"},
+		{"UTF-8 without cpg", "", 0, []byte("Zürich"), "Zürich"},
+		{"Shift_JIS cpg", "SJIS", 0, []byte("\x93\x8c\x8b\x9e"), "東京"},
+		{"CP932 cpg", "932", 0, []byte("\x93\x8c\x8b\x9e"), "東京"},
+		{"Shift_JIS language driver", "", 0x13, []byte("\x93\x8c\x8b\x9e"), "東京"},
+		{"Windows-1252 cpg", "1252", 0, []byte("\x80 \x93quoted\x94"), "€ “quoted”"},
+		{"ANSI 1252 cpg", "ANSI 1252", 0, []byte("caf\xe9"), "café"},
+		{"ISO-8859-1 cpg", "ISO-8859-1", 0, []byte("caf\xe9"), "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diags Diagnostics
+			var cpg []byte
+			if tt.cpg != "" {
+				cpg = []byte(tt.cpg)
+			}
+			table, err := readDBF(nameDBF(tt.languageDriver, tt.raw), cpg, &diags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := table.records[0]["NAME"]; got != tt.want {
+				t.Errorf("NAME = %q, want %q", got, tt.want)
+			}
+			if len(diags) != 0 {
+				t.Errorf("diagnostics = %v", diags)
+			}
+		})
+	}
+}
+
+func TestReadDBFUnknownCodePage(t *testing.T) {
+	var diags Diagnostics
+	table, err := readDBF(nameDBF(0, []byte("caf\xe9")), []byte("x-unknown"), &diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := table.records[0]["NAME"]; got != "café" {
+		t.Errorf("NAME = %q, want the Latin-1 fallback", got)
+	}
+	if !hasDiagnostic(diags, SeverityError, "/cpg", "unsupported encoding") {
+		t.Errorf("diagnostics = %v, want an error for the code page", diags)
+	}
+}
+
+func TestReadDBFLatin1Fallback(t *testing.T) {
+	var diags Diagnostics
+	table, err := readDBF(nameDBF(0, []byte("caf\xe9")), nil, &diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := table.records[0]["NAME"]; got != "café" {
+		t.Errorf("NAME = %q, want café", got)
+	}
+	if !hasDiagnostic(diags, SeverityWarning, "/dbf", "Latin-1") {
+		t.Errorf("diagnostics = %v, want the Latin-1 warning", diags)
+	}
+}
+
+func TestReadDBFTruncated(t *testing.T) {
+	var diags Diagnostics
+	dbf := nameDBF(0, []byte("a"), []byte("b"))
+	table, err := readDBF(dbf[:len(dbf)-5], nil, &diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.records) != 1 {
+		t.Errorf("%d records, want 1", len(table.records))
+	}
+	if !hasDiagnostic(diags, SeverityError, "/dbf/records/1", "truncated") {
+		t.Errorf("diagnostics = %v, want the truncated record", diags)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+# diagnostics.go
+
+読み込み時に見つかった問題（不正な座標や未対応のジオメトリなど）の記録
+*/
+package geo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 診断の重大度
+type Severity int
+
+const (
+	// データは読めたが、宣言との食い違いなど確認が必要なもの
+	SeverityWarning Severity = iota
+	// 読み飛ばした座標やフィーチャーがあるもの
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// 読み込み時の診断1件
+type Diagnostic struct {
+	Severity Severity
+	// 問題のある値の位置（RFC 6901 のJSON Pointer。例: "/features/12/geometry/coordinates/0/3"）
+	// ルート自体なら空文字。シェープファイルでは "/records/3"（4番目のレコード）や "/dbf"・"/prj" のようになる
+	Pointer string
+	Message string
+}
+
+// "error /features/12/geometry: message" の形式で返す
+func (d Diagnostic) String() string {
+	pointer := d.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%s %s: %s", d.Severity, pointer, d.Message)
+}
+
+// 読み込み時の診断の並び（見つかった順）
+type Diagnostics []Diagnostic
+
+// 重大度がseverityの診断の数
+func (d Diagnostics) Count(severity Severity) int {
+	count := 0
+	for _, diagnostic := range d {
+		if diagnostic.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// エラーの診断が1件でもあればtrue
+func (d Diagnostics) HasErrors() bool {
+	return d.Count(SeverityError) > 0
+}
+
+// "2 errors, 1 warning" の形式で件数をまとめる
+func (d Diagnostics) Summary() string {
+	plural := func(n int, word string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, word)
+		}
+		return fmt.Sprintf("%d %ss", n, word)
+	}
+	return plural(d.Count(SeverityError), "error") + ", " + plural(d.Count(SeverityWarning), "warning")
+}
+
+// 診断を追加する。dがnilなら何もしない（診断が不要な呼び出し用）
+func (d *Diagnostics) add(severity Severity, pointer, format string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	*d = append(*d, Diagnostic{Severity: severity, Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *Diagnostics) errorf(pointer, format string, args ...interface{}) {
+	d.add(SeverityError, pointer, format, args...)
+}
+
+func (d *Diagnostics) warnf(pointer, format string, args ...interface{}) {
+	d.add(SeverityWarning, pointer, format, args...)
+}
+
+// 相対位置の診断をprefixの下に追加する
+func (d *Diagnostics) addAll(prefix string, relative Diagnostics) {
+	if d == nil {
+		return
+	}
+	for _, diagnostic := range relative {
+		diagnostic.Pointer = prefix + diagnostic.Pointer
+		*d = append(*d, diagnostic)
+	}
+}
+
+// JSON Pointerにメンバー名を1つ足す（RFC 6901 のエスケープ付き）
+func pointerKey(pointer, key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	key = strings.ReplaceAll(key, "/", "~1")
+	return pointer + "/" + key
+}
+
+// JSON Pointerに配列の添字を1つ足す
+func pointerIndex(pointer string, index int) string {
+	return pointer + "/" + strconv.Itoa(index)
+}
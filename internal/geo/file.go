@@ -0,0 +1,128 @@
+/*
+# file.go
+
+ファイルを拡張子に応じた形式で読み込むモジュール
+*/
+package geo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+ReadFile
+ファイルを拡張子に応じた形式で読み込み、地理座標をLayer型で返す。
+
+	.shp: シェープファイル（ReadShapefile）
+	.zip: zipに入ったシェープファイル（ReadShapefileZip）
+	.kml: KML（ReadKML）
+	.kmz: KMZ（ReadKMZ）
+	.gpx: GPX（ReadGPX）
+	.wkt・.ewkt: 1行に1つのWKT・EWKT・16進数のWKB（ReadWKTLines）
+	その他: GeoJSON（ReadLayer）
+
+TopoJSON（.json・.topojson）はパスの後ろに "#オブジェクト名" を付けると、そのオブジェクトだけを読む
+（ReadTopologyObject。付けなければ全オブジェクトを1つのレイヤーにまとめる）。
+
+Args:
+
+	path: ファイルのパス（TopoJSONなら "counties.topojson#states" の形も可）
+	progress: 進捗の通知先（nilなら通知しない）。GeoJSONの場合だけ通知する
+
+Returns:
+
+	Layer
+*/
+func ReadFile(path string, progress func(Progress)) (Layer, error) {
+	if base, object, ok := splitObjectPath(path); ok {
+		file, err := os.Open(base)
+		if err != nil {
+			return Layer{Valid: false}, fmt.Errorf("read file: %w", err)
+		}
+		defer file.Close()
+		layer, err := ReadTopologyObject(file, object)
+		if err != nil {
+			return layer, fmt.Errorf("read TopoJSON: %w", err)
+		}
+		return layer, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".shp":
+		layer, err := ReadShapefile(path)
+		if err != nil {
+			return layer, fmt.Errorf("read shapefile: %w", err)
+		}
+		return layer, nil
+	case ".zip":
+		layer, err := ReadShapefileZip(path)
+		if err != nil {
+			return layer, fmt.Errorf("read shapefile: %w", err)
+		}
+		return layer, nil
+	case ".kmz":
+		layer, err := ReadKMZ(path)
+		if err != nil {
+			return layer, fmt.Errorf("read KMZ: %w", err)
+		}
+		return layer, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Layer{Valid: false}, fmt.Errorf("read file: %w", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".kml":
+		layer, err := ReadKML(file)
+		if err != nil {
+			return layer, fmt.Errorf("read KML: %w", err)
+		}
+		return layer, nil
+	case ".gpx":
+		layer, err := ReadGPX(file)
+		if err != nil {
+			return layer, fmt.Errorf("read GPX: %w", err)
+		}
+		return layer, nil
+	case ".wkt", ".ewkt":
+		layer, err := ReadWKTLines(file)
+		if err != nil {
+			return layer, fmt.Errorf("read WKT: %w", err)
+		}
+		return layer, nil
+	}
+	layer, err := ReadLayer(file, progress)
+	if err != nil {
+		return layer, fmt.Errorf("read GeoJSON: %w", err)
+	}
+	return layer, nil
+}
+
+// "counties.topojson#states" をファイルのパスとオブジェクト名に分ける。
+// "#" の前が実在する .json・.topojson のファイルのときだけ分け、
+// それ以外（"#" を含む名前のファイルや打ち間違い）はパス全体を1つのファイルとして扱う
+func splitObjectPath(path string) (string, string, bool) {
+	i := strings.LastIndex(path, "#")
+	if i < 0 {
+		return "", "", false
+	}
+	base := path[:i]
+	switch strings.ToLower(filepath.Ext(base)) {
+	case ".json", ".topojson":
+	default:
+		return "", "", false
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", "", false
+	}
+	if info, err := os.Stat(base); err != nil || info.IsDir() {
+		return "", "", false
+	}
+	return base, path[i+1:], true
+}
@@ -0,0 +1,423 @@
+/*
+# geometry.go
+
+GeoJSONのジオメトリとフィーチャーの型付きモデル
+UnmarshalJSON で座標を interface{} を経由せずに読み、境界ボックスも同じパスで計算する
+*/
+package geo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ジオメトリの種類（RFC 7946 3.1）
+type GeometryType string
+
+const (
+	GeometryPoint              GeometryType = "Point"
+	GeometryMultiPoint         GeometryType = "MultiPoint"
+	GeometryLineString         GeometryType = "LineString"
+	GeometryMultiLineString    GeometryType = "MultiLineString"
+	GeometryPolygon            GeometryType = "Polygon"
+	GeometryMultiPolygon       GeometryType = "MultiPolygon"
+	GeometryGeometryCollection GeometryType = "GeometryCollection"
+)
+
+// RFC 7946 のジオメトリ型ならtrue
+func (t GeometryType) Valid() bool {
+	switch t {
+	case GeometryPoint, GeometryMultiPoint, GeometryLineString, GeometryMultiLineString,
+		GeometryPolygon, GeometryMultiPolygon, GeometryGeometryCollection:
+		return true
+	}
+	return false
+}
+
+// デコード済みのジオメトリ
+type Geometry struct {
+	// ジオメトリの種類。オブジェクトでない・未対応の型など読めなかった場合は空
+	Type GeometryType
+	// 外周リングと穴の組（Point/MultiPoint/Polygon/MultiPolygon）。Pointは頂点が1つだけの外周リング
+	Parts []CachedPart
+	// 開いたパス（LineString/MultiLineString）
+	Lines [][][2]float64
+	// Lines と同じ順の各ラインのZ・M。どのラインにも無ければnil（Parts のZ・Mは CachedPart.Measures）
+	LineMeasures []PathMeasures
+	// GeometryCollection のメンバー（読めなかったメンバーも Type が空のまま位置を保つ）
+	Geometries []Geometry
+	// 全頂点（メンバーを含む）の境界ボックス。頂点が無ければ無限大を逆向きにした範囲
+	Bounds Bound
+	// デコード時の診断。Pointer はこのジオメトリからの相対位置（メンバーの診断は含まない）
+	Diagnostics Diagnostics
+}
+
+// UnmarshalJSON はジオメトリをデコードする。
+// 不正な値はエラーにせず Diagnostics に記録する（JSONの構文エラーはデコーダが返す）
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil || members == nil {
+		*g = Geometry{Bounds: emptyBound()}
+		g.Diagnostics.errorf("", "geometry is %s, not an object; skipped", rawKind(data))
+		return nil
+	}
+	*g = geometryFromMembers(members)
+	return nil
+}
+
+// メンバーの診断も含めた全診断（Pointer はこのジオメトリからの相対位置）
+func (g Geometry) AllDiagnostics() Diagnostics {
+	diags := append(Diagnostics(nil), g.Diagnostics...)
+	for i, member := range g.Geometries {
+		diags.addAll(pointerIndex("/geometries", i), member.AllDiagnostics())
+	}
+	return diags
+}
+
+// ポリゴン（点を含む）とライン、ラインのZ・Mに分解する。GeometryCollection はメンバーを再帰的にまとめる
+func (g Geometry) Flatten() (parts []CachedPart, lines [][][2]float64, lineMeasures []PathMeasures) {
+	if g.Type != GeometryGeometryCollection {
+		return g.Parts, g.Lines, g.LineMeasures
+	}
+	for _, member := range g.Geometries {
+		memberParts, memberLines, memberMeasures := member.Flatten()
+		parts = append(parts, memberParts...)
+		for i, line := range memberLines {
+			var measures PathMeasures
+			if i < len(memberMeasures) {
+				measures = memberMeasures[i]
+			}
+			lineMeasures = appendPathMeasures(lineMeasures, len(lines), measures)
+			lines = append(lines, line)
+		}
+	}
+	return parts, lines, lineMeasures
+}
+
+// オブジェクトのメンバーからジオメトリを組み立てる
+func geometryFromMembers(members map[string]json.RawMessage) Geometry {
+	g := Geometry{Bounds: emptyBound()}
+	var typeValue interface{}
+	if raw, ok := members["type"]; ok {
+		_ = json.Unmarshal(raw, &typeValue)
+	}
+	typeName, _ := typeValue.(string)
+	if typeValue == nil {
+		g.Diagnostics.errorf("", "geometry has no type; skipped")
+		return g
+	}
+	if !GeometryType(typeName).Valid() {
+		g.Diagnostics.errorf("/type", "unsupported geometry type %s", jsonKind(typeValue))
+		return g
+	}
+	g.Type = GeometryType(typeName)
+
+	if g.Type == GeometryGeometryCollection {
+		var list []json.RawMessage
+		raw := members["geometries"]
+		if err := json.Unmarshal(raw, &list); err != nil || list == nil {
+			g.Diagnostics.errorf("/geometries", "expected an array of geometries, got %s", rawKind(raw))
+			return g
+		}
+		g.Geometries = make([]Geometry, len(list))
+		for i, memberRaw := range list {
+			_ = g.Geometries[i].UnmarshalJSON(memberRaw)
+			g.Bounds = g.Bounds.union(g.Geometries[i].Bounds)
+		}
+		return g
+	}
+
+	coords, ok := members["coordinates"]
+	if !ok {
+		g.Diagnostics.errorf("", "%s has no coordinates", g.Type)
+		return g
+	}
+	if !g.decodeCoordinates(coords) {
+		// 型どおりにデコードできなければ、1つずつ検証して読める座標だけを拾う
+		g.Parts, g.Lines, g.LineMeasures, g.Bounds = nil, nil, nil, emptyBound()
+		var value interface{}
+		if err := json.Unmarshal(coords, &value); err != nil && value == nil {
+			g.Diagnostics.errorf("/coordinates", "cannot decode coordinates: %v", err)
+			return g
+		}
+		// 範囲外の数値（1e400 など）があってもデコードは続き、その位置は toCoordinatePair が不正として記録する
+		g.Parts, g.Lines, g.LineMeasures = parseCoordinates(g.Type, value, "/coordinates", &g.Diagnostics)
+		extendBound(&g.Bounds, g.Parts, g.Lines)
+	}
+	return g
+}
+
+// 座標をジオメトリの型どおりに1パスで読み、境界ボックスも同時に広げる。
+// 不正な座標（数値でない・2次元未満・空の配列・null）が1つでもあればfalseを返す
+func (g *Geometry) decodeCoordinates(raw json.RawMessage) bool {
+	s := coordinateScanner{data: raw, bounds: &g.Bounds}
+	ok := false
+	switch g.Type {
+	case GeometryPoint:
+		position, z, m, positionOK := s.position()
+		ok = positionOK
+		g.Parts = []CachedPart{{Exterior: [][2]float64{position}, Measures: pointMeasures(z, m)}}
+	case GeometryMultiPoint:
+		ok = s.array(func() bool {
+			position, z, m, ok := s.position()
+			g.Parts = append(g.Parts, CachedPart{Exterior: [][2]float64{position}, Measures: pointMeasures(z, m)})
+			return ok
+		})
+	case GeometryLineString:
+		path, measures, pathOK := s.path()
+		ok = pathOK
+		g.Lines = [][][2]float64{path}
+		g.LineMeasures = appendPathMeasures(nil, 0, measures)
+	case GeometryMultiLineString:
+		ok = s.array(func() bool {
+			path, measures, ok := s.path()
+			g.LineMeasures = appendPathMeasures(g.LineMeasures, len(g.Lines), measures)
+			g.Lines = append(g.Lines, path)
+			return ok
+		})
+	case GeometryPolygon:
+		var part CachedPart
+		part, ok = s.polygon()
+		g.Parts = []CachedPart{part}
+	case GeometryMultiPolygon:
+		ok = s.array(func() bool {
+			part, ok := s.polygon()
+			g.Parts = append(g.Parts, part)
+			return ok
+		})
+	}
+	return ok && s.end()
+}
+
+// 座標配列（数値の入れ子配列）だけを読む簡易スキャナ。
+// 入力はJSONとして検証済みのものを前提とし、数値以外の値や空の配列は不正として扱う
+type coordinateScanner struct {
+	data   []byte
+	pos    int
+	bounds *Bound
+}
+
+func (s *coordinateScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// 次の文字がcなら読み進めてtrueを返す
+func (s *coordinateScanner) consume(c byte) bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// 入力を最後まで読んだならtrue
+func (s *coordinateScanner) end() bool {
+	s.skipSpace()
+	return s.pos == len(s.data)
+}
+
+// '[' item (',' item)* ']' を読む（空の配列は不正）
+func (s *coordinateScanner) array(item func() bool) bool {
+	if !s.consume('[') {
+		return false
+	}
+	for {
+		if !item() {
+			return false
+		}
+		if s.consume(']') {
+			return true
+		}
+		if !s.consume(',') {
+			return false
+		}
+	}
+}
+
+func (s *coordinateScanner) number() (float64, bool) {
+	s.skipSpace()
+	start := s.pos
+	for s.pos < len(s.data) && strings.IndexByte("+-.0123456789eE", s.data[s.pos]) >= 0 {
+		s.pos++
+	}
+	if start == s.pos {
+		return 0, false
+	}
+	// 範囲外の数値はParseFloatがエラーと±Infを返す。有限の値だけを受け付ける
+	v, err := strconv.ParseFloat(string(s.data[start:s.pos]), 64)
+	return v, err == nil && isFinite(v)
+}
+
+// 位置 [lon, lat, z, m] を読み、境界ボックスを広げる。
+// 高さzと計測値mは無ければNaN（5つ目以降の値は読み捨てる）
+func (s *coordinateScanner) position() (position [2]float64, z, m float64, ok bool) {
+	values := [4]float64{0, 0, math.NaN(), math.NaN()}
+	n := 0
+	ok = s.array(func() bool {
+		v, ok := s.number()
+		if n < len(values) {
+			values[n] = v
+		}
+		n++
+		return ok
+	})
+	position = [2]float64{values[0], values[1]}
+	if !ok || n < 2 {
+		return position, values[2], values[3], false
+	}
+	s.bounds.extend(position[0], position[1])
+	return position, values[2], values[3], true
+}
+
+// 位置の並び（ライン・リング）を読む
+func (s *coordinateScanner) path() ([][2]float64, PathMeasures, bool) {
+	var path [][2]float64
+	var measures PathMeasures
+	ok := s.array(func() bool {
+		position, z, m, ok := s.position()
+		measures.Z = appendMeasure(measures.Z, len(path), z)
+		measures.M = appendMeasure(measures.M, len(path), m)
+		path = append(path, position)
+		return ok
+	})
+	return path, measures, ok
+}
+
+// リングの並び（[外周, 穴, 穴, ...]）をポリゴンの1パートとして読む
+func (s *coordinateScanner) polygon() (CachedPart, bool) {
+	var part CachedPart
+	rings := 0
+	ok := s.array(func() bool {
+		ring, measures, ok := s.path()
+		if rings == 0 {
+			part.Exterior = ring
+		} else {
+			part.Holes = append(part.Holes, ring)
+		}
+		part.Measures = appendPathMeasures(part.Measures, rings, measures)
+		rings++
+		return ok
+	})
+	return part, ok
+}
+
+// デコード済みのフィーチャー
+type Feature struct {
+	// ジオメトリ。null・省略・読めなかった場合はnil
+	Geometry *Geometry
+	// プロパティ。null・省略なら空のmap
+	Properties map[string]interface{}
+	// bboxメンバーで宣言された境界ボックス。宣言がない・不正ならnil
+	BBox *Bound
+	// デコード時の診断。Pointer はこのフィーチャーからの相対位置
+	Diagnostics Diagnostics
+	// オブジェクトとして読めたかどうか
+	Valid bool
+}
+
+// UnmarshalJSON はフィーチャーをデコードする。
+// 不正な値はエラーにせず Diagnostics に記録する（JSONの構文エラーはデコーダが返す）
+func (f *Feature) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil || members == nil {
+		*f = Feature{}
+		f.Diagnostics.errorf("", "feature is %s, not an object; skipped", rawKind(data))
+		return nil
+	}
+	*f = featureFromMembers(members)
+	return nil
+}
+
+// オブジェクトのメンバーからフィーチャーを組み立てる
+func featureFromMembers(members map[string]json.RawMessage) Feature {
+	f := Feature{Valid: true, Properties: map[string]interface{}{}}
+
+	if raw, ok := members["geometry"]; !ok || isJSONNull(raw) {
+		f.Diagnostics.warnf("/geometry", "feature has no geometry; skipped")
+	} else {
+		var geometry Geometry
+		_ = geometry.UnmarshalJSON(raw)
+		f.Diagnostics.addAll("/geometry", geometry.AllDiagnostics())
+		if geometry.Type != "" {
+			f.Geometry = &geometry
+		}
+	}
+
+	// RFC 7946 ではpropertiesの省略・nullも有効
+	if raw, ok := members["properties"]; ok && !isJSONNull(raw) {
+		var properties map[string]interface{}
+		if err := json.Unmarshal(raw, &properties); err != nil {
+			if kind := rawKind(raw); kind != "object" {
+				f.Diagnostics.errorf("/properties", "properties is %s, not an object; ignored", kind)
+			} else {
+				// オブジェクトでも値が読めないことがある（float64の範囲を超える数値など）
+				f.Diagnostics.errorf("/properties", "cannot decode properties: %v; ignored", err)
+			}
+		} else {
+			f.Properties = properties
+		}
+	}
+
+	if raw, ok := members["bbox"]; ok {
+		var value interface{}
+		_ = json.Unmarshal(raw, &value)
+		declared, err := parseBBox(value)
+		if err != nil {
+			f.Diagnostics.warnf("/bbox", "ignoring bbox: %v", err)
+		} else {
+			f.BBox = declared
+		}
+	}
+	return f
+}
+
+// ジオメトリを空のpropertiesを持つ合成フィーチャーで包む
+func geometryFeature(geometry Geometry) Feature {
+	f := Feature{Valid: true, Properties: map[string]interface{}{}, Diagnostics: geometry.AllDiagnostics()}
+	if geometry.Type != "" {
+		f.Geometry = &geometry
+	}
+	return f
+}
+
+// JSONのnullならtrue
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
+
+// デコード前のJSONの値の種類を診断メッセージ用に返す（jsonKind と同じ表記）
+func rawKind(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "nothing"
+	}
+	switch raw[0] {
+	case 'n':
+		return "null"
+	case 't', 'f':
+		return "boolean"
+	case '[':
+		return "array"
+	case '{':
+		return "object"
+	case '"':
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return fmt.Sprintf("%q", s)
+		}
+		return "string"
+	}
+	return "number"
+}
@@ -0,0 +1,101 @@
+package geo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// pointer の位置に message を含む診断があればtrue
+func hasDiagnostic(diags Diagnostics, severity Severity, pointer, message string) bool {
+	for _, d := range diags {
+		if d.Severity == severity && d.Pointer == pointer && strings.Contains(d.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeGeometry(t *testing.T, text string) Geometry {
+	t.Helper()
+	var g Geometry
+	if err := json.Unmarshal([]byte(text), &g); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", text, err)
+	}
+	return g
+}
+
+func TestGeometryRejectsOutOfRangeNumbers(t *testing.T) {
+	tests := []struct {
+		text    string
+		pointer string
+	}{
+		{`{"type":"Point","coordinates":[1e400,2]}`, "/coordinates"},
+		{`{"type":"LineString","coordinates":[[1e400,2],[3,4],[5,6]]}`, "/coordinates/0"},
+		{`{"type":"MultiPoint","coordinates":[[1,2],[3,-1e400]]}`, "/coordinates/1"},
+		{`{"type":"LineString","coordinates":[[0,0,1e400],[3,4],[5,6]]}`, "/coordinates/0"},
+	}
+	for _, tt := range tests {
+		g := decodeGeometry(t, tt.text)
+		if !hasDiagnostic(g.Diagnostics, SeverityError, tt.pointer, "finite") {
+			t.Errorf("%s: diagnostics = %v, want an error at %s", tt.text, g.Diagnostics, tt.pointer)
+		}
+		if g.Bounds.LonMax > 1e300 || g.Bounds.LatMin < -1e300 {
+			t.Errorf("%s: bounds = %+v include the out-of-range value", tt.text, g.Bounds)
+		}
+	}
+}
+
+func TestReadLayerOutOfRangeOnlyFeature(t *testing.T) {
+	text := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[1e400,2]}}]}`
+	layer, err := ReadLayer(strings.NewReader(text), nil)
+	if err == nil {
+		t.Fatalf("ReadLayer succeeded with bounds %+v, want error", layer.Bounds)
+	}
+	if !hasDiagnostic(layer.Diagnostics, SeverityError, "/features/0/geometry/coordinates", "finite") {
+		t.Errorf("diagnostics = %v, want the out-of-range position", layer.Diagnostics)
+	}
+}
+
+func TestGeometryDropsDegeneratePaths(t *testing.T) {
+	g := decodeGeometry(t, `{"type":"LineString","coordinates":[[0,0],[1,"x"]]}`)
+	if len(g.Lines) != 0 {
+		t.Errorf("LineString kept %v", g.Lines)
+	}
+	if !hasDiagnostic(g.Diagnostics, SeverityError, "/coordinates", "need 2") {
+		t.Errorf("diagnostics = %v, want the degenerate line", g.Diagnostics)
+	}
+
+	g = decodeGeometry(t, `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]],[[0.2,0.2],null]]}`)
+	if len(g.Parts) != 1 || len(g.Parts[0].Holes) != 0 {
+		t.Fatalf("Parts = %v, want the exterior without holes", g.Parts)
+	}
+	if !hasDiagnostic(g.Diagnostics, SeverityError, "/coordinates/1", "need 4") {
+		t.Errorf("diagnostics = %v, want the degenerate hole", g.Diagnostics)
+	}
+
+	// 読み飛ばした座標があっても十分な数が残れば保つ
+	g = decodeGeometry(t, `{"type":"MultiLineString","coordinates":[[[0,0],"x",[1,1]],[[2,2],[3,3]]]}`)
+	if len(g.Lines) != 2 || len(g.Lines[0]) != 2 {
+		t.Errorf("Lines = %v, want both lines", g.Lines)
+	}
+}
+
+func TestFeaturePropertiesDiagnostics(t *testing.T) {
+	tests := []struct {
+		text    string
+		message string
+	}{
+		{`{"type":"Feature","geometry":null,"properties":[1,2]}`, "properties is array, not an object"},
+		{`{"type":"Feature","geometry":null,"properties":{"x":1e400}}`, "cannot decode properties"},
+	}
+	for _, tt := range tests {
+		var f Feature
+		if err := json.Unmarshal([]byte(tt.text), &f); err != nil {
+			t.Fatal(err)
+		}
+		if !hasDiagnostic(f.Diagnostics, SeverityError, "/properties", tt.message) {
+			t.Errorf("%s: diagnostics = %v, want %q", tt.text, f.Diagnostics, tt.message)
+		}
+	}
+}
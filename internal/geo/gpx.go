@@ -0,0 +1,242 @@
+/*
+# gpx.go
+
+GPSの記録（GPX 1.1）を読み込み、Layer型に変換するモジュール
+*/
+package geo
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GPXの点（wpt・trkpt・rtept）
+type gpxPoint struct {
+	Lat  string `xml:"lat,attr"`
+	Lon  string `xml:"lon,attr"`
+	Ele  string `xml:"ele"`
+	Time string `xml:"time"`
+	Name string `xml:"name"`
+	Desc string `xml:"desc"`
+	Type string `xml:"type"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Desc     string       `xml:"desc"`
+	Type     string       `xml:"type"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Desc   string     `xml:"desc"`
+	Type   string     `xml:"type"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+/*
+ReadGPX
+GPX 1.1を読み込み、trk・rte・wptごとのフィーチャーを持つLayerを返す（文書中の順）。
+
+	trk: trksegごとのラインを持つLineString（複数ならMultiLineString）
+	rte: rteptを結んだLineString
+	wpt: Point
+
+eleはZとして保持する。propertiesには name・desc・type と、
+wptなら time・ele、trk・rteなら最初と最後の点の time（time・end_time）が入る。
+診断の位置は "/trk/0/trkseg/1/trkpt/5" の形式になる。
+*/
+func ReadGPX(r io.Reader) (Layer, error) {
+	builder := newLayerBuilder()
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = xmlCharsetReader(&builder.diags)
+	counts := map[string]int{}
+	root := true
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Layer{Valid: false}, fmt.Errorf("parse XML: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			if start.Name.Local != "gpx" {
+				return Layer{Valid: false}, fmt.Errorf("GPX root is <%s>, not <gpx>", start.Name.Local)
+			}
+			root = false
+			continue
+		}
+
+		var feature Feature
+		switch start.Name.Local {
+		case "trk":
+			var track gpxTrack
+			if err := dec.DecodeElement(&track, &start); err != nil {
+				return Layer{Valid: false}, fmt.Errorf("parse XML: %w", err)
+			}
+			feature = gpxTrackFeature(track)
+		case "rte":
+			var route gpxRoute
+			if err := dec.DecodeElement(&route, &start); err != nil {
+				return Layer{Valid: false}, fmt.Errorf("parse XML: %w", err)
+			}
+			feature = gpxRouteFeature(route)
+		case "wpt":
+			var point gpxPoint
+			if err := dec.DecodeElement(&point, &start); err != nil {
+				return Layer{Valid: false}, fmt.Errorf("parse XML: %w", err)
+			}
+			feature = gpxWaypointFeature(point)
+		default:
+			// metadataや拡張要素は読み飛ばす
+			if err := dec.Skip(); err != nil {
+				return Layer{Valid: false}, fmt.Errorf("parse XML: %w", err)
+			}
+			continue
+		}
+		pointer := pointerIndex("/"+start.Name.Local, counts[start.Name.Local])
+		counts[start.Name.Local]++
+		builder.addFeature(feature, pointer)
+	}
+	if root {
+		return Layer{Valid: false}, errors.New("empty GPX")
+	}
+	if counts["trk"]+counts["rte"]+counts["wpt"] == 0 {
+		return Layer{Valid: false}, errors.New("tracks, routes or waypoints not found in GPX")
+	}
+	return builder.finish(nil)
+}
+
+// 空でない値だけをプロパティに入れる
+func gpxProperties(name, desc, kind string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for key, value := range map[string]string{"name": name, "desc": desc, "type": kind} {
+		if value = strings.TrimSpace(value); value != "" {
+			properties[key] = value
+		}
+	}
+	return properties
+}
+
+func gpxTrackFeature(track gpxTrack) Feature {
+	feature := Feature{Valid: true, Properties: gpxProperties(track.Name, track.Desc, track.Type)}
+	g := Geometry{Type: GeometryMultiLineString, Bounds: emptyBound()}
+	if len(track.Segments) == 0 {
+		feature.Diagnostics.warnf("", "track has no segments; skipped")
+	}
+	var times []string
+	for i, segment := range track.Segments {
+		path, measures, segmentTimes := gpxPath(segment.Points, pointerIndex("/trkseg", i)+"/trkpt", &feature.Diagnostics)
+		if len(path) < 2 {
+			feature.Diagnostics.errorf(pointerIndex("/trkseg", i), "segment has fewer than 2 valid points; skipped")
+			continue
+		}
+		g.LineMeasures = appendPathMeasures(g.LineMeasures, len(g.Lines), measures)
+		g.Lines = append(g.Lines, path)
+		times = append(times, segmentTimes...)
+	}
+	return gpxLineFeature(feature, g, times)
+}
+
+func gpxRouteFeature(route gpxRoute) Feature {
+	feature := Feature{Valid: true, Properties: gpxProperties(route.Name, route.Desc, route.Type)}
+	g := Geometry{Type: GeometryLineString, Bounds: emptyBound()}
+	path, measures, times := gpxPath(route.Points, "/rtept", &feature.Diagnostics)
+	if len(path) < 2 {
+		feature.Diagnostics.errorf("", "route has fewer than 2 valid points; skipped")
+	} else {
+		g.Lines = [][][2]float64{path}
+		g.LineMeasures = appendPathMeasures(nil, 0, measures)
+	}
+	return gpxLineFeature(feature, g, times)
+}
+
+// ラインのジオメトリと、最初と最後の点の時刻をフィーチャーに設定する
+func gpxLineFeature(feature Feature, g Geometry, times []string) Feature {
+	if len(g.Lines) == 0 {
+		return feature
+	}
+	if g.Type == GeometryMultiLineString && len(g.Lines) == 1 {
+		g.Type = GeometryLineString
+	}
+	extendBound(&g.Bounds, nil, g.Lines)
+	feature.Geometry = &g
+	if len(times) > 0 {
+		feature.Properties["time"] = times[0]
+		feature.Properties["end_time"] = times[len(times)-1]
+	}
+	return feature
+}
+
+func gpxWaypointFeature(point gpxPoint) Feature {
+	feature := Feature{Valid: true, Properties: gpxProperties(point.Name, point.Desc, point.Type)}
+	position, z, ok := gpxPosition(point, "", &feature.Diagnostics)
+	if !ok {
+		return feature
+	}
+	if t := strings.TrimSpace(point.Time); t != "" {
+		feature.Properties["time"] = t
+	}
+	if !math.IsNaN(z) {
+		feature.Properties["ele"] = z
+	}
+	g := Geometry{Type: GeometryPoint, Bounds: emptyBound()}
+	g.Parts = []CachedPart{{Exterior: [][2]float64{position}, Measures: pointMeasures(z, math.NaN())}}
+	extendBound(&g.Bounds, g.Parts, nil)
+	feature.Geometry = &g
+	return feature
+}
+
+// 点の並びをラインにする。不正な点は読み飛ばし、時刻は記録のある点のものだけを返す
+func gpxPath(points []gpxPoint, pointer string, diags *Diagnostics) ([][2]float64, PathMeasures, []string) {
+	var path [][2]float64
+	var measures PathMeasures
+	var times []string
+	for i, point := range points {
+		position, z, ok := gpxPosition(point, pointerIndex(pointer, i), diags)
+		if !ok {
+			continue
+		}
+		measures.Z = appendMeasure(measures.Z, len(path), z)
+		path = append(path, position)
+		if t := strings.TrimSpace(point.Time); t != "" {
+			times = append(times, t)
+		}
+	}
+	return path, measures, times
+}
+
+// 点の経度緯度と標高（無ければNaN）を読む。NaN・Infと範囲外の経度緯度は不正とする
+func gpxPosition(point gpxPoint, pointer string, diags *Diagnostics) ([2]float64, float64, bool) {
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(point.Lon), 64)
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(point.Lat), 64)
+	if errLon != nil || errLat != nil || !isLonLat(lon, lat) {
+		diags.errorf(pointer, "lat=%q lon=%q is not a valid position; skipped", point.Lat, point.Lon)
+		return [2]float64{}, 0, false
+	}
+	z := math.NaN()
+	if ele := strings.TrimSpace(point.Ele); ele != "" {
+		v, err := strconv.ParseFloat(ele, 64)
+		if err != nil || !isFinite(v) {
+			diags.warnf(pointer+"/ele", "elevation %q is not a number; ignored", ele)
+		} else {
+			z = v
+		}
+	}
+	return [2]float64{lon, lat}, z, true
+}
@@ -0,0 +1,32 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadGPXRejectsInvalidPositions(t *testing.T) {
+	gpx := `<gpx>
+		<wpt lat="NaN" lon="Inf"/>
+		<wpt lat="95" lon="400"/>
+		<wpt lat="35" lon="139"><ele>Inf</ele></wpt>
+	</gpx>`
+	layer, err := ReadGPX(strings.NewReader(gpx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || layer.Bounds != (Bound{LonMin: 139, LonMax: 139, LatMin: 35, LatMax: 35}) {
+		t.Fatalf("%d features with bounds %+v, want only the valid waypoint", len(layer.Features), layer.Bounds)
+	}
+	for _, pointer := range []string{"/wpt/0", "/wpt/1"} {
+		if !hasDiagnostic(layer.Diagnostics, SeverityError, pointer, "not a valid position") {
+			t.Errorf("diagnostics = %v, want an error at %s", layer.Diagnostics, pointer)
+		}
+	}
+	if !hasDiagnostic(layer.Diagnostics, SeverityWarning, "/wpt/2/ele", "not a number") {
+		t.Errorf("diagnostics = %v, want the infinite elevation ignored", layer.Diagnostics)
+	}
+	if layer.Elevation != nil {
+		t.Errorf("Elevation = %+v, want none", layer.Elevation)
+	}
+}
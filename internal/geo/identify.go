@@ -0,0 +1,133 @@
+/*
+# identify.go
+
+経度緯度からその位置にあるフィーチャーを探す
+*/
+package geo
+
+import "math"
+
+// FeatureAt は (lon, lat) にあるフィーチャーのインデックスを返す。
+// 後に描画されるフィーチャーほど上にあるとみなし、末尾から探す。
+// ポリゴンは内部（穴を除く）に含まれるか、ライン・ポイント・輪郭は tolerance（度）以内にあれば一致とする。
+func (l Layer) FeatureAt(lon, lat, tolerance float64) (int, bool) {
+	p := [2]float64{lon, lat}
+	for i := len(l.Features) - 1; i >= 0; i-- {
+		if featureContains(l.Features[i], p, tolerance) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// FeatureNear は (lon, lat) にあるフィーチャー（FeatureAtと同じ判定）のインデックスを返す。
+// 該当するものがなければ、最も近いフィーチャーを返す（同距離なら上にあるもの）。
+func (l Layer) FeatureNear(lon, lat, tolerance float64) (int, bool) {
+	if i, ok := l.FeatureAt(lon, lat, tolerance); ok {
+		return i, true
+	}
+	p := [2]float64{lon, lat}
+	nearest := -1
+	best := math.Inf(1)
+	for i := len(l.Features) - 1; i >= 0; i-- {
+		if d := featureDistance(l.Features[i], p); d < best {
+			nearest, best = i, d
+		}
+	}
+	return nearest, nearest >= 0
+}
+
+// フィーチャーの全リング・ラインまでの最短距離
+func featureDistance(feature CachedFeature, p [2]float64) float64 {
+	best := math.Inf(1)
+	for _, part := range feature.Parts {
+		for _, ring := range part.Rings() {
+			best = math.Min(best, pathDistance(ring, p, true))
+		}
+	}
+	for _, line := range feature.Lines {
+		best = math.Min(best, pathDistance(line, p, false))
+	}
+	return best
+}
+
+func featureContains(feature CachedFeature, p [2]float64, tolerance float64) bool {
+	for _, part := range feature.Parts {
+		if partContains(part, p) {
+			return true
+		}
+		for _, ring := range part.Rings() {
+			if pathDistance(ring, p, true) <= tolerance {
+				return true
+			}
+		}
+	}
+	for _, line := range feature.Lines {
+		if pathDistance(line, p, false) <= tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// パートの外周に含まれ、どの穴にも含まれなければtrue
+func partContains(part CachedPart, p [2]float64) bool {
+	if !ringContains(part.Exterior, p) {
+		return false
+	}
+	for _, hole := range part.Holes {
+		if ringContains(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// 交差数判定（even-odd）による点とリングの内外判定
+func ringContains(ring [][2]float64, p [2]float64) bool {
+	n := len(ring)
+	if n < 3 {
+		return false
+	}
+	inside := false
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) {
+			x := a[0] + (p[1]-a[1])/(b[1]-a[1])*(b[0]-a[0])
+			if p[0] < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// 点からパスまでの最短距離（経度緯度をそのまま平面座標として扱う）
+func pathDistance(path [][2]float64, p [2]float64, closed bool) float64 {
+	if len(path) == 0 {
+		return math.Inf(1)
+	}
+	if len(path) == 1 {
+		return math.Hypot(p[0]-path[0][0], p[1]-path[0][1])
+	}
+	best := math.Inf(1)
+	for i := 1; i < len(path); i++ {
+		best = math.Min(best, segmentDistance(path[i-1], path[i], p))
+	}
+	if closed {
+		best = math.Min(best, segmentDistance(path[len(path)-1], path[0], p))
+	}
+	return best
+}
+
+// 点から線分abまでの最短距離
+func segmentDistance(a, b, p [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	lengthSq := dx*dx + dy*dy
+	t := 0.0
+	if lengthSq > 0 {
+		t = ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / lengthSq
+		t = math.Max(0, math.Min(1, t))
+	}
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
@@ -0,0 +1,61 @@
+/*
+# jgd2011.go
+
+JGD2011 平面直角座標系（I〜XIX系、EPSG:6669〜6687）
+*/
+package geo
+
+import "fmt"
+
+const (
+	// I系のEPSGコード。XIX系まで連番
+	jgd2011ZoneBaseEPSG = 6669
+	// 平面直角座標系の座標系原点における縮尺係数
+	jgd2011ScaleFactor = 0.9999
+)
+
+// 各系の座標系原点（緯度, 経度）。添字0がI系
+var jgd2011Origins = [19][2]float64{
+	{33, 129 + 30.0/60}, // I
+	{33, 131},           // II
+	{36, 132 + 10.0/60}, // III
+	{33, 133 + 30.0/60}, // IV
+	{36, 134 + 20.0/60}, // V
+	{36, 136},           // VI
+	{36, 137 + 10.0/60}, // VII
+	{36, 138 + 30.0/60}, // VIII
+	{36, 139 + 50.0/60}, // IX
+	{40, 140 + 50.0/60}, // X
+	{44, 140 + 15.0/60}, // XI
+	{44, 142 + 15.0/60}, // XII
+	{44, 144 + 15.0/60}, // XIII
+	{26, 142},           // XIV
+	{26, 127 + 30.0/60}, // XV
+	{26, 124},           // XVI
+	{26, 131},           // XVII
+	{20, 136},           // XVIII
+	{26, 154},           // XIX
+}
+
+var romanNumerals = [19]string{
+	"I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX", "X",
+	"XI", "XII", "XIII", "XIV", "XV", "XVI", "XVII", "XVIII", "XIX",
+}
+
+// JGD2011Zone は平面直角座標系の系番号（1〜19）の横メルカトル図法を返す
+// 座標は (x, y) = (東距, 北距)（m）で、測量の慣習（X=北, Y=東）とは軸の名前が逆になる
+func JGD2011Zone(zone int) (TransverseMercator, error) {
+	if zone < 1 || zone > len(jgd2011Origins) {
+		return TransverseMercator{}, fmt.Errorf("JGD2011 zone out of range (1-19): %d", zone)
+	}
+	origin := jgd2011Origins[zone-1]
+	return NewTransverseMercator(GRS80, origin[1], origin[0], jgd2011ScaleFactor, 0, 0), nil
+}
+
+// JGD2011ZoneName は系番号をローマ数字の名前（例: "IX"）にする
+func JGD2011ZoneName(zone int) string {
+	if zone < 1 || zone > len(romanNumerals) {
+		return fmt.Sprint(zone)
+	}
+	return romanNumerals[zone-1]
+}
@@ -0,0 +1,307 @@
+/*
+# kml.go
+
+KML（Google Earthなど）とKMZ（KMLのzip）を読み込み、Layer型に変換するモジュール
+*/
+package geo
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// KMLのジオメトリ要素（PlacemarkとMultiGeometryの子）
+type kmlGeometries struct {
+	Points        []kmlCoordinates     `xml:"Point"`
+	LineStrings   []kmlCoordinates     `xml:"LineString"`
+	LinearRings   []kmlCoordinates     `xml:"LinearRing"`
+	Polygons      []kmlPolygon         `xml:"Polygon"`
+	MultiGeometry []kmlMultiGeometries `xml:"MultiGeometry"`
+}
+
+type kmlMultiGeometries struct {
+	kmlGeometries
+}
+
+// coordinates要素を持つジオメトリ（Point・LineString・LinearRing）
+type kmlCoordinates struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	Outer kmlCoordinates   `xml:"outerBoundaryIs>LinearRing"`
+	Inner []kmlCoordinates `xml:"innerBoundaryIs>LinearRing"`
+}
+
+type kmlPlacemark struct {
+	Name         string `xml:"name"`
+	Description  string `xml:"description"`
+	ExtendedData struct {
+		Data []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value"`
+		} `xml:"Data"`
+		SchemaData []struct {
+			SimpleData []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"SimpleData"`
+		} `xml:"SchemaData"`
+	} `xml:"ExtendedData"`
+	kmlGeometries
+}
+
+// プロパティ（name・description・ExtendedDataのData/SimpleData）。空の値は含めない
+func (p kmlPlacemark) properties() map[string]interface{} {
+	properties := map[string]interface{}{}
+	set := func(key, value string) {
+		if key != "" && strings.TrimSpace(value) != "" {
+			properties[key] = strings.TrimSpace(value)
+		}
+	}
+	set("name", p.Name)
+	set("description", p.Description)
+	for _, data := range p.ExtendedData.Data {
+		set(data.Name, data.Value)
+	}
+	for _, schema := range p.ExtendedData.SchemaData {
+		for _, data := range schema.SimpleData {
+			set(data.Name, data.Value)
+		}
+	}
+	return properties
+}
+
+/*
+ReadKML
+KMLを読み込み、Placemarkごとのフィーチャーを持つLayerを返す（Document・Folderの入れ子は問わない）。
+
+Point・LineString・LinearRing（閉じたライン）・Polygon（innerBoundaryIsは穴）・MultiGeometryに対応し、
+高度はZとして保持する。name・description・ExtendedDataはpropertiesに入る。
+診断の位置は "/placemarks/3/Polygon/0"（4番目のPlacemarkの最初のPolygon）の形式になる。
+*/
+func ReadKML(r io.Reader) (Layer, error) {
+	builder := newLayerBuilder()
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = xmlCharsetReader(&builder.diags)
+	placemarks := 0
+	root := true
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Layer{Valid: false}, fmt.Errorf("parse XML: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			if start.Name.Local != "kml" {
+				return Layer{Valid: false}, fmt.Errorf("KML root is <%s>, not <kml>", start.Name.Local)
+			}
+			root = false
+			continue
+		}
+		if start.Name.Local != "Placemark" {
+			continue
+		}
+		var placemark kmlPlacemark
+		if err := dec.DecodeElement(&placemark, &start); err != nil {
+			return Layer{Valid: false}, fmt.Errorf("parse XML: %w", err)
+		}
+		pointer := pointerIndex("/placemarks", placemarks)
+		placemarks++
+
+		feature := Feature{Valid: true, Properties: placemark.properties()}
+		geometry := kmlGeometry(placemark.kmlGeometries, &feature.Diagnostics)
+		if geometry.Type == "" {
+			feature.Diagnostics.warnf("", "placemark has no geometry; skipped")
+		} else {
+			feature.Geometry = &geometry
+		}
+		builder.addFeature(feature, pointer)
+	}
+	if root {
+		return Layer{Valid: false}, errors.New("empty KML")
+	}
+	if placemarks == 0 {
+		return Layer{Valid: false}, errors.New("placemarks not found in KML")
+	}
+	return builder.finish(nil)
+}
+
+/*
+ReadKMZ
+KMZ（KMLとその画像などをまとめたzip）を読み込む。
+zipの中の doc.kml、無ければ最初（パス順）の .kml を ReadKML で読む。
+*/
+func ReadKMZ(path string) (Layer, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return Layer{Valid: false}, fmt.Errorf("open zip: %w", err)
+	}
+	defer archive.Close()
+
+	var main *zip.File
+	for _, file := range archive.File {
+		name := strings.ToLower(file.Name)
+		if !strings.HasSuffix(name, ".kml") {
+			continue
+		}
+		if name == "doc.kml" || strings.HasSuffix(name, "/doc.kml") {
+			main = file
+			break
+		}
+		if main == nil || name < strings.ToLower(main.Name) {
+			main = file
+		}
+	}
+	if main == nil {
+		return Layer{Valid: false}, errors.New("no .kml file in KMZ")
+	}
+	rc, err := main.Open()
+	if err != nil {
+		return Layer{Valid: false}, fmt.Errorf("read %s: %w", main.Name, err)
+	}
+	defer rc.Close()
+	return ReadKML(rc)
+}
+
+/*
+Placemark（またはMultiGeometry）のジオメトリ要素を1つのジオメトリにする。
+要素が1つならその型、複数ならGeometryCollectionになり、要素が無ければTypeが空になる。
+診断は Placemark・MultiGeometry からの相対位置で記録する。
+*/
+func kmlGeometry(elements kmlGeometries, diags *Diagnostics) Geometry {
+	var members []Geometry
+	add := func(g Geometry, ok bool) {
+		if ok {
+			members = append(members, g)
+		}
+	}
+	for i, point := range elements.Points {
+		add(kmlPoint(point, pointerIndex("/Point", i), diags))
+	}
+	for i, line := range elements.LineStrings {
+		add(kmlLine(line, pointerIndex("/LineString", i), diags))
+	}
+	for i, ring := range elements.LinearRings {
+		add(kmlLine(ring, pointerIndex("/LinearRing", i), diags))
+	}
+	for i, polygon := range elements.Polygons {
+		add(kmlPolygonGeometry(polygon, pointerIndex("/Polygon", i), diags))
+	}
+	for i, multi := range elements.MultiGeometry {
+		var memberDiags Diagnostics
+		g := kmlGeometry(multi.kmlGeometries, &memberDiags)
+		diags.addAll(pointerIndex("/MultiGeometry", i), memberDiags)
+		add(g, g.Type != "")
+	}
+
+	switch len(members) {
+	case 0:
+		return Geometry{Bounds: emptyBound()}
+	case 1:
+		return members[0]
+	}
+	g := Geometry{Type: GeometryGeometryCollection, Geometries: members, Bounds: emptyBound()}
+	for _, member := range members {
+		g.Bounds = g.Bounds.union(member.Bounds)
+	}
+	return g
+}
+
+func kmlPoint(element kmlCoordinates, pointer string, diags *Diagnostics) (Geometry, bool) {
+	path, measures, ok := parseKMLCoordinates(element.Coordinates, pointer, diags)
+	if !ok {
+		return Geometry{}, false
+	}
+	g := Geometry{Type: GeometryPoint, Bounds: emptyBound()}
+	z := math.NaN()
+	if measures.Z != nil {
+		z = measures.Z[0]
+	}
+	g.Parts = []CachedPart{{Exterior: path[:1], Measures: pointMeasures(z, math.NaN())}}
+	extendBound(&g.Bounds, g.Parts, nil)
+	return g, true
+}
+
+func kmlLine(element kmlCoordinates, pointer string, diags *Diagnostics) (Geometry, bool) {
+	path, measures, ok := parseKMLCoordinates(element.Coordinates, pointer, diags)
+	if !ok {
+		return Geometry{}, false
+	}
+	g := Geometry{Type: GeometryLineString, Lines: [][][2]float64{path}, LineMeasures: appendPathMeasures(nil, 0, measures), Bounds: emptyBound()}
+	extendBound(&g.Bounds, nil, g.Lines)
+	return g, true
+}
+
+func kmlPolygonGeometry(element kmlPolygon, pointer string, diags *Diagnostics) (Geometry, bool) {
+	exterior, measures, ok := parseKMLCoordinates(element.Outer.Coordinates, pointer+"/outerBoundaryIs", diags)
+	if !ok {
+		diags.errorf(pointer, "invalid outer boundary; polygon skipped")
+		return Geometry{}, false
+	}
+	part := CachedPart{Exterior: exterior, Measures: appendPathMeasures(nil, 0, measures)}
+	for i, inner := range element.Inner {
+		hole, measures, ok := parseKMLCoordinates(inner.Coordinates, pointerIndex(pointer+"/innerBoundaryIs", i), diags)
+		if !ok {
+			continue
+		}
+		part.Measures = appendPathMeasures(part.Measures, len(part.Holes)+1, measures)
+		part.Holes = append(part.Holes, hole)
+	}
+	g := Geometry{Type: GeometryPolygon, Parts: []CachedPart{part}, Bounds: emptyBound()}
+	extendBound(&g.Bounds, g.Parts, nil)
+	return g, true
+}
+
+/*
+KMLのcoordinates（"lon,lat[,alt]" を空白で区切った並び）をパースする。
+不正な組（NaN・Infや経度緯度の範囲外を含む）は読み飛ばし、有効な座標が1つも無ければ失敗とする。高度はZになる。
+*/
+func parseKMLCoordinates(text, pointer string, diags *Diagnostics) ([][2]float64, PathMeasures, bool) {
+	var path [][2]float64
+	var measures PathMeasures
+	for i, tuple := range strings.Fields(text) {
+		values := strings.Split(tuple, ",")
+		position, z, ok := [2]float64{}, math.NaN(), len(values) >= 2
+		for j := 0; ok && j < len(values) && j < 3; j++ {
+			v, err := strconv.ParseFloat(values[j], 64)
+			switch {
+			case err != nil:
+				ok = false
+			case j < 2:
+				position[j] = v
+			case isFinite(v):
+				z = v
+			default:
+				ok = false
+			}
+		}
+		if !ok {
+			diags.errorf(pointerIndex(pointer+"/coordinates", i), "coordinate %q is not lon,lat[,alt]; skipped", tuple)
+			continue
+		}
+		if !isLonLat(position[0], position[1]) {
+			diags.errorf(pointerIndex(pointer+"/coordinates", i), "coordinate %q is not a finite lon,lat within range; skipped", tuple)
+			continue
+		}
+		measures.Z = appendMeasure(measures.Z, len(path), z)
+		path = append(path, position)
+	}
+	if len(path) == 0 {
+		diags.errorf(pointer, "no valid coordinates")
+		return nil, PathMeasures{}, false
+	}
+	return path, measures, true
+}
@@ -0,0 +1,28 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadKMLRejectsInvalidCoordinates(t *testing.T) {
+	kml := `<kml><Placemark><name>line</name><LineString><coordinates>
+		139,35 NaN,Inf 200,10 10,-95 140,36,Inf 141,37,10
+	</coordinates></LineString></Placemark></kml>`
+	layer, err := ReadKML(strings.NewReader(kml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || len(layer.Features[0].Lines[0]) != 2 {
+		t.Fatalf("features = %+v, want a line of the 2 valid coordinates", layer.Features)
+	}
+	if layer.Bounds != (Bound{LonMin: 139, LonMax: 141, LatMin: 35, LatMax: 37}) {
+		t.Errorf("Bounds = %+v, want only the valid coordinates", layer.Bounds)
+	}
+	for i, message := range map[int]string{1: "within range", 2: "within range", 3: "within range", 4: "not lon,lat"} {
+		pointer := pointerIndex("/placemarks/0/LineString/0/coordinates", i)
+		if !hasDiagnostic(layer.Diagnostics, SeverityError, pointer, message) {
+			t.Errorf("diagnostics = %v, want %q at %s", layer.Diagnostics, message, pointer)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+# measures.go
+
+位置の3つ目以降の値（高さZ・計測値M）の保持
+座標そのものは [経度, 緯度] のまま扱い、Z・Mはパスと同じ並びの別スライスに持つ
+*/
+package geo
+
+import "math"
+
+// パスの各頂点の高さ（Z）と計測値（M）。添字はパスの頂点と同じ
+// 値の無い頂点はNaN、パスのどの頂点にも値が無ければnil
+type PathMeasures struct {
+	Z []float64
+	M []float64
+}
+
+// ZもMも無ければtrue
+func (m PathMeasures) empty() bool {
+	return m.Z == nil && m.M == nil
+}
+
+// 値の範囲
+type Range struct {
+	Min, Max float64
+}
+
+// 値が1つも無い範囲（extendで広げる前の初期値）
+func emptyRange() Range {
+	return Range{Min: math.Inf(1), Max: math.Inf(-1)}
+}
+
+// NaNを除いた値で範囲を広げる
+func (r *Range) extend(values []float64) {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		r.Min = math.Min(r.Min, v)
+		r.Max = math.Max(r.Max, v)
+	}
+}
+
+// 値が1つでも含まれていればtrue
+func (r Range) valid() bool {
+	return r.Min <= r.Max
+}
+
+/*
+i番目の頂点の値vを追加する。
+値（NaN以外）が初めて現れたときにスライスを作り、それまでの頂点をNaNで埋める。
+2次元の座標だけのパスではnilのまま割り当てが発生しない。
+*/
+func appendMeasure(values []float64, i int, v float64) []float64 {
+	if values == nil {
+		if math.IsNaN(v) {
+			return nil
+		}
+		values = make([]float64, i, i+1)
+		for j := range values {
+			values[j] = math.NaN()
+		}
+	}
+	return append(values, v)
+}
+
+// i番目のパスのZ・Mを追加する。appendMeasure と同じく、値のあるパスが現れるまではnilのまま
+func appendPathMeasures(list []PathMeasures, i int, m PathMeasures) []PathMeasures {
+	if list == nil {
+		if m.empty() {
+			return nil
+		}
+		list = make([]PathMeasures, i, i+1)
+	}
+	return append(list, m)
+}
+
+// 頂点1つ（Point）のZ・M。どちらも無ければnil
+func pointMeasures(z, m float64) []PathMeasures {
+	return appendPathMeasures(nil, 0, PathMeasures{Z: appendMeasure(nil, 0, z), M: appendMeasure(nil, 0, m)})
+}
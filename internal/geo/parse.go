@@ -1,116 +1,331 @@
 package geo
 
-import "math"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
 
-func toCoordinatePair(value interface{}) (float64, float64, bool) {
+// 位置 [lon, lat, z, m] を取り出す。高さzと計測値mは無い・数値でなければNaN。
+// float64の範囲を超える値（1e400 など）は不正とする
+func toCoordinatePair(value interface{}) (position [2]float64, z, m float64, ok bool) {
+	z, m = math.NaN(), math.NaN()
 	pair, ok := value.([]interface{})
 	if !ok || len(pair) < 2 {
-		return 0, 0, false
+		return position, z, m, false
 	}
 	lon, okLon := pair[0].(float64)
 	lat, okLat := pair[1].(float64)
-	if !okLon || !okLat {
-		return 0, 0, false
+	if !okLon || !okLat || !isFinite(lon) || !isFinite(lat) {
+		return position, z, m, false
 	}
-	return lon, lat, true
+	if len(pair) > 2 {
+		if v, ok := pair[2].(float64); ok {
+			if !isFinite(v) {
+				return position, z, m, false
+			}
+			z = v
+		}
+	}
+	if len(pair) > 3 {
+		if v, ok := pair[3].(float64); ok {
+			if !isFinite(v) {
+				return position, z, m, false
+			}
+			m = v
+		}
+	}
+	return [2]float64{lon, lat}, z, m, true
+}
+
+func isFinite(v float64) bool {
+	return !math.IsInf(v, 0) && !math.IsNaN(v)
 }
 
-func parseRing(value interface{}) ([][2]float64, bool) {
+// 有限で経度±180度・緯度±90度に収まる経度緯度ならtrue（KML・GPXのように常にWGS84の形式で使う）
+func isLonLat(lon, lat float64) bool {
+	return isFinite(lon) && isFinite(lat) && math.Abs(lon) <= 180 && math.Abs(lat) <= 90
+}
+
+const (
+	// ラインとリングに必要な座標の数
+	minLinePositions = 2
+	minRingPositions = 4
+)
+
+// 座標の並び（リング・ライン）をパースする。
+// 不正な座標は読み飛ばし、位置をdiagsに記録する。有効な座標が1つも無ければ失敗とする。
+// 読み飛ばした結果 minPositions 個に満たなくなった並びも、ラインやリングにならないため失敗とする。
+func parseRing(value interface{}, minPositions int, pointer string, diags *Diagnostics) ([][2]float64, PathMeasures, bool) {
 	ringSlice, ok := value.([]interface{})
 	if !ok {
-		return nil, false
+		diags.errorf(pointer, "expected an array of positions, got %s", jsonKind(value))
+		return nil, PathMeasures{}, false
 	}
 	var ring [][2]float64
-	for _, coord := range ringSlice {
-		lon, lat, ok := toCoordinatePair(coord)
+	var measures PathMeasures
+	for i, coord := range ringSlice {
+		position, z, m, ok := toCoordinatePair(coord)
 		if !ok {
+			diags.errorf(pointerIndex(pointer, i), "position is not a pair of finite numbers; skipped")
 			continue
 		}
-		ring = append(ring, [2]float64{lon, lat})
+		measures.Z = appendMeasure(measures.Z, len(ring), z)
+		measures.M = appendMeasure(measures.M, len(ring), m)
+		ring = append(ring, position)
 	}
 	if len(ring) == 0 {
-		return nil, false
+		diags.errorf(pointer, "no valid positions")
+		return nil, PathMeasures{}, false
 	}
-	return ring, true
+	if len(ring) < len(ringSlice) && len(ring) < minPositions {
+		diags.errorf(pointer, "only %d valid positions left, need %d; skipped", len(ring), minPositions)
+		return nil, PathMeasures{}, false
+	}
+	return ring, measures, true
 }
 
-func extractCoordinates(geometry map[string]interface{}) [][][2]float64 {
-	// ジオメトリがnilの場合はnilを返す
-	if geometry == nil {
-		return nil
+// ポリゴンの座標配列（[外周, 穴, 穴, ...]）をパースする。
+// 外周が不正な場合は失敗とし、不正な穴は読み飛ばす。
+func parsePolygon(value interface{}, pointer string, diags *Diagnostics) (CachedPart, bool) {
+	ringsSlice, ok := value.([]interface{})
+	if !ok || len(ringsSlice) == 0 {
+		diags.errorf(pointer, "expected an array of linear rings")
+		return CachedPart{}, false
 	}
-
-	// ジオメトリタイプと座標の取得
-	geomTypeField := geometry["type"]
-	geomType, _ := geomTypeField.(string)
-	coordsField, ok := geometry["coordinates"]
+	exterior, measures, ok := parseRing(ringsSlice[0], minRingPositions, pointerIndex(pointer, 0), diags)
 	if !ok {
-		return nil
+		diags.errorf(pointer, "invalid exterior ring; polygon skipped")
+		return CachedPart{}, false
 	}
+	part := CachedPart{Exterior: exterior, Measures: appendPathMeasures(nil, 0, measures)}
+	for i, holeField := range ringsSlice[1:] {
+		if hole, measures, ok := parseRing(holeField, minRingPositions, pointerIndex(pointer, i+1), diags); ok {
+			part.Measures = appendPathMeasures(part.Measures, len(part.Holes)+1, measures)
+			part.Holes = append(part.Holes, hole)
+		}
+	}
+	return part, true
+}
+
+// 型どおりにデコードできなかった座標（coordinates）を1つずつ検証しながら抽出する。
+// parts は外周リングと穴の組（Point/MultiPoint/Polygon/MultiPolygon）、lines は開いたパス（LineString/MultiLineString）、
+// lineMeasures は lines のZ・M。
+// 読み飛ばした値は pointer（coordinatesの位置）からのJSON Pointerとともにdiagsに記録する。
+func parseCoordinates(geomType GeometryType, coordsField interface{}, pointer string, diags *Diagnostics) (parts []CachedPart, lines [][][2]float64, lineMeasures []PathMeasures) {
 	coordsSlice, ok := coordsField.([]interface{})
 	if !ok || len(coordsSlice) == 0 {
-		return nil
+		diags.errorf(pointer, "expected a non-empty array, got %s", jsonKind(coordsField))
+		return nil, nil, nil
 	}
 
 	// ジオメトリタイプに応じた座標の抽出
 	switch geomType {
-	case "Point":
-		if lon, lat, ok := toCoordinatePair(coordsSlice); ok {
-			return [][][2]float64{{{lon, lat}}}
+	case GeometryPoint:
+		if position, z, m, ok := toCoordinatePair(coordsSlice); ok {
+			return []CachedPart{{Exterior: [][2]float64{position}, Measures: pointMeasures(z, m)}}, nil, nil
 		}
-	case "Polygon":
-		if ring, ok := parseRing(coordsSlice[0]); ok {
-			return [][][2]float64{ring}
-		}
-	case "MultiPolygon":
-		var result [][][2]float64
-		for _, poly := range coordsSlice {
-			polyRings, ok := poly.([]interface{})
-			if !ok || len(polyRings) == 0 {
+		diags.errorf(pointer, "position is not a pair of finite numbers")
+	case GeometryMultiPoint:
+		for i, pointField := range coordsSlice {
+			if position, z, m, ok := toCoordinatePair(pointField); ok {
+				parts = append(parts, CachedPart{Exterior: [][2]float64{position}, Measures: pointMeasures(z, m)})
 				continue
 			}
-			if ring, ok := parseRing(polyRings[0]); ok {
-				result = append(result, ring)
+			diags.errorf(pointerIndex(pointer, i), "position is not a pair of finite numbers; skipped")
+		}
+		return parts, nil, nil
+	case GeometryLineString:
+		if line, measures, ok := parseRing(coordsSlice, minLinePositions, pointer, diags); ok {
+			return nil, [][][2]float64{line}, appendPathMeasures(nil, 0, measures)
+		}
+	case GeometryMultiLineString:
+		for i, lineField := range coordsSlice {
+			if line, measures, ok := parseRing(lineField, minLinePositions, pointerIndex(pointer, i), diags); ok {
+				lineMeasures = appendPathMeasures(lineMeasures, len(lines), measures)
+				lines = append(lines, line)
 			}
 		}
-		return result
+		return nil, lines, lineMeasures
+	case GeometryPolygon:
+		if part, ok := parsePolygon(coordsSlice, pointer, diags); ok {
+			return []CachedPart{part}, nil, nil
+		}
+	case GeometryMultiPolygon:
+		for i, poly := range coordsSlice {
+			if part, ok := parsePolygon(poly, pointerIndex(pointer, i), diags); ok {
+				parts = append(parts, part)
+			}
+		}
+		return parts, nil, nil
 	}
 
-	return nil
+	return nil, nil, nil
 }
 
-func calculateBoundingBox(features []map[string]interface{}) *Bound {
-	positiveInf := math.Inf(1)
-	negativeInf := math.Inf(-1)
+// JSONの値の種類を診断メッセージ用に返す（文字列は値そのもの）
+func jsonKind(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
 
-	bound := &Bound{
-		LonMin: positiveInf,
-		LonMax: negativeInf,
-		LatMin: positiveInf,
-		LatMax: negativeInf,
+// どの点も含まない境界ボックス（extend・union の初期値）
+func emptyBound() Bound {
+	return Bound{
+		LonMin: math.Inf(1),
+		LonMax: math.Inf(-1),
+		LatMin: math.Inf(1),
+		LatMax: math.Inf(-1),
 	}
+}
+
+// 点 (lon, lat) を含むように境界ボックスを広げる
+func (b *Bound) extend(lon, lat float64) {
+	b.LonMin = math.Min(b.LonMin, lon)
+	b.LonMax = math.Max(b.LonMax, lon)
+	b.LatMin = math.Min(b.LatMin, lat)
+	b.LatMax = math.Max(b.LatMax, lat)
+}
+
+// 2つの境界ボックスをどちらも含む境界ボックス
+func (b Bound) union(other Bound) Bound {
+	return Bound{
+		LonMin: math.Min(b.LonMin, other.LonMin),
+		LonMax: math.Max(b.LonMax, other.LonMax),
+		LatMin: math.Min(b.LatMin, other.LatMin),
+		LatMax: math.Max(b.LatMax, other.LatMax),
+	}
+}
 
-	// ジオメトリの抽出とバウンディングボックスの更新
+// 経度緯度に変換済みのフィーチャー群から境界ボックスを計算する
+func calculateFeaturesBound(features []CachedFeature) *Bound {
+	bound := emptyBound()
 	for _, feature := range features {
-		geometryField, ok := feature["geometry"]
-		if !ok {
-			continue
+		extendBound(&bound, feature.Parts, feature.Lines)
+	}
+	return &bound
+}
+
+// ポリゴンとラインの全頂点を含むように境界ボックスを広げる
+func extendBound(bound *Bound, parts []CachedPart, lines [][][2]float64) {
+	extend := func(path [][2]float64) {
+		for _, coord := range path {
+			bound.extend(coord[0], coord[1])
 		}
-		geometry, ok := geometryField.(map[string]interface{})
-		if !ok {
-			continue
+	}
+	for _, part := range parts {
+		for _, ring := range part.Rings() {
+			extend(ring)
 		}
-		coordinates := extractCoordinates(geometry)
-		for _, rings := range coordinates {
-			for _, ring := range rings {
-				lon, lat := ring[0], ring[1]
-				bound.LonMin = math.Min(bound.LonMin, lon)
-				bound.LonMax = math.Max(bound.LonMax, lon)
-				bound.LatMin = math.Min(bound.LatMin, lat)
-				bound.LatMax = math.Max(bound.LatMax, lat)
-			}
+	}
+	for _, line := range lines {
+		extend(line)
+	}
+}
+
+/*
+parseCRSMember
+GeoJSON 2008 の crs メンバーからCRSの名前を取り出す。宣言がなければ空文字を返す。
+
+対応する形式:
+
+	{"type": "name", "properties": {"name": "urn:ogc:def:crs:EPSG::6677"}}
+	{"type": "EPSG", "properties": {"code": 6677}}
+*/
+func parseCRSMember(value interface{}) string {
+	crs, ok := value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	properties, ok := crs["properties"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch crsType, _ := crs["type"].(string); strings.ToLower(crsType) {
+	case "name":
+		name, _ := properties["name"].(string)
+		return strings.TrimSpace(name)
+	case "epsg":
+		switch code := properties["code"].(type) {
+		case float64:
+			return fmt.Sprintf("EPSG:%d", int(code))
+		case string:
+			return "EPSG:" + strings.TrimSpace(code)
 		}
 	}
-	return bound
+	return ""
+}
 
+/*
+parseBBox
+bbox メンバーを境界ボックスとして解釈する。
+2次元 [west, south, east, north] と3次元 [west, south, zmin, east, north, zmax] に対応する。
+日付変更線をまたぐbbox（west > east、RFC 7946 5.2）は東端を+360°して返す。
+*/
+func parseBBox(value interface{}) (*Bound, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("bbox is not an array")
+	}
+	if len(values) != 4 && len(values) != 6 {
+		return nil, fmt.Errorf("bbox must have 4 or 6 numbers, got %d", len(values))
+	}
+	numbers := make([]float64, len(values))
+	for i, v := range values {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("bbox[%d] is not a number", i)
+		}
+		numbers[i] = n
+	}
+	dim := len(numbers) / 2
+	bound := &Bound{
+		LonMin: numbers[0],
+		LatMin: numbers[1],
+		LonMax: numbers[dim],
+		LatMax: numbers[dim+1],
+	}
+	if bound.LatMin > bound.LatMax {
+		return nil, fmt.Errorf("bbox minimum exceeds maximum: %v", numbers)
+	}
+	if bound.LonMin > bound.LonMax {
+		bound.LonMax += 360
+	}
+	return bound, nil
+}
+
+// bboxの許容誤差（計算した範囲の幅・高さに対する割合）
+const bboxTolerance = 0.01
+
+// 宣言されたbboxが計算した境界ボックスと一致しないならtrue（範囲の1%までの差は許容する）
+func boundsDisagree(declared, computed Bound) bool {
+	tolerance := bboxTolerance*math.Max(computed.lonSpan(), computed.latSpan()) + 1e-9
+	return math.Abs(declared.LonMin-computed.LonMin) > tolerance ||
+		math.Abs(declared.LonMax-computed.LonMax) > tolerance ||
+		math.Abs(declared.LatMin-computed.LatMin) > tolerance ||
+		math.Abs(declared.LatMax-computed.LatMax) > tolerance
+}
+
+// 警告表示用に境界ボックスを [west, south, east, north] の形式で整形する
+// 日付変更線をまたぐ範囲は west > east の形式（RFC 7946 5.2）にする
+func formatBBox(b Bound) string {
+	west, east := b.LonMin, b.LonMax
+	if b.CrossesAntimeridian() {
+		west, east = NormalizeLon(west), NormalizeLon(east)
+	}
+	return fmt.Sprintf("[%.6g, %.6g, %.6g, %.6g]", west, b.LatMin, east, b.LatMax)
 }
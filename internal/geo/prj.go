@@ -0,0 +1,257 @@
+/*
+# prj.go
+
+シェープファイルの .prj（ESRI WKT / OGC WKT 1）からCRSの名前を求めるモジュール
+*/
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WKTの要素 NAME[arg, arg, ...]。引数は文字列・数値（string/float64）・入れ子の要素
+type wktNode struct {
+	Name string
+	Args []interface{}
+}
+
+// 名前がnameの最初の子要素（大文字・小文字は区別しない）
+func (n *wktNode) child(name string) *wktNode {
+	for _, arg := range n.Args {
+		if node, ok := arg.(*wktNode); ok && strings.EqualFold(node.Name, name) {
+			return node
+		}
+	}
+	return nil
+}
+
+// i番目の引数が文字列ならその値
+func (n *wktNode) text(i int) string {
+	if n == nil || i >= len(n.Args) {
+		return ""
+	}
+	s, _ := n.Args[i].(string)
+	return s
+}
+
+// i番目の引数が数値ならその値
+func (n *wktNode) number(i int) (float64, bool) {
+	if n == nil || i >= len(n.Args) {
+		return 0, false
+	}
+	v, ok := n.Args[i].(float64)
+	return v, ok
+}
+
+// WKTの構文を読む簡易パーサ
+type wktParser struct {
+	text string
+	pos  int
+}
+
+func parseWKT(text string) (*wktNode, error) {
+	p := &wktParser{text: text}
+	node, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.text) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.text[p.pos:p.pos+1], p.pos)
+	}
+	return node, nil
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.text) && strings.IndexByte(" \t\r\n", p.text[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// NAME[...] または NAME(...) を読む
+func (p *wktParser) node() (*wktNode, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.text) && (isWKTNameByte(p.text[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, fmt.Errorf("expected a keyword at offset %d", start)
+	}
+	node := &wktNode{Name: p.text[start:p.pos]}
+	p.skipSpace()
+	if p.pos >= len(p.text) || (p.text[p.pos] != '[' && p.text[p.pos] != '(') {
+		return nil, fmt.Errorf("expected '[' after %s", node.Name)
+	}
+	closing := byte(']')
+	if p.text[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.text) {
+			return nil, errors.New("unexpected end of WKT")
+		}
+		if p.text[p.pos] == closing {
+			p.pos++
+			return node, nil
+		}
+		if len(node.Args) > 0 {
+			if p.text[p.pos] != ',' {
+				return nil, fmt.Errorf("expected ',' at offset %d", p.pos)
+			}
+			p.pos++
+			p.skipSpace()
+		}
+		arg, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		node.Args = append(node.Args, arg)
+	}
+}
+
+// 引数（"文字列"・数値・入れ子の要素・AXISのNORTHのような裸の名前）を読む
+func (p *wktParser) value() (interface{}, error) {
+	if p.pos >= len(p.text) {
+		return nil, errors.New("unexpected end of WKT")
+	}
+	switch c := p.text[p.pos]; {
+	case c == '"':
+		end := strings.IndexByte(p.text[p.pos+1:], '"')
+		if end < 0 {
+			return nil, errors.New("unterminated string in WKT")
+		}
+		s := p.text[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return s, nil
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.text) && strings.IndexByte("+-.0123456789eE", p.text[p.pos]) >= 0 {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.text[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in WKT", p.text[start:p.pos])
+		}
+		return v, nil
+	}
+	start := p.pos
+	for p.pos < len(p.text) && isWKTNameByte(p.text[p.pos]) {
+		p.pos++
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) && (p.text[p.pos] == '[' || p.text[p.pos] == '(') {
+		p.pos = start
+		return p.node()
+	}
+	if start == p.pos {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.text[p.pos:p.pos+1], p.pos)
+	}
+	return strings.TrimSpace(p.text[start:p.pos]), nil
+}
+
+func isWKTNameByte(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+var (
+	// ESRIの平面直角座標系の名前（例: "JGD_2011_Japan_Zone_9"）
+	prjJGD2011Zone = regexp.MustCompile(`(?i)^JGD_?2011_Japan_Zone_(\d+)$`)
+	// ESRIのUTMの名前（例: "WGS_1984_UTM_Zone_54N"）
+	prjWGS84UTMZone = regexp.MustCompile(`(?i)^WGS_?1984_UTM_Zone_(\d+)([NS])$`)
+)
+
+/*
+prjCRSName
+.prj のWKTを ParseCRS で読める名前に変換する。
+
+ 1. 最上位の AUTHORITY["EPSG", "6677"] があり ParseCRS が対応していれば "EPSG:6677"
+ 2. GEOGCS（経度緯度）: 測地系がJGD2011なら "JGD2011"、それ以外は "WGS84"
+ 3. PROJCS: ESRIの平面直角座標系・UTMの名前なら "JGD2011:9" / "UTM:54N"、
+    それ以外の横メルカトル図法はパラメータから "+proj=tmerc ..." を組み立てる
+
+経度緯度の測地系の違い（WGS84とJGD2011以外）は無視する。
+*/
+func prjCRSName(text string) (string, error) {
+	root, err := parseWKT(strings.TrimSpace(text))
+	if err != nil {
+		return "", fmt.Errorf("parse WKT: %w", err)
+	}
+	if authority := root.child("AUTHORITY"); authority != nil && strings.EqualFold(authority.text(0), "EPSG") {
+		name := "EPSG:" + strings.TrimSpace(authority.text(1))
+		if _, err := ParseCRS(name); err == nil {
+			return name, nil
+		}
+	}
+
+	switch strings.ToUpper(root.Name) {
+	case "GEOGCS", "GEOGCRS":
+		return geogcsName(root), nil
+	case "PROJCS", "PROJCRS":
+	default:
+		return "", fmt.Errorf("unsupported WKT: %s", root.Name)
+	}
+
+	name := root.text(0)
+	if m := prjJGD2011Zone.FindStringSubmatch(name); m != nil {
+		return "JGD2011:" + m[1], nil
+	}
+	if m := prjWGS84UTMZone.FindStringSubmatch(name); m != nil {
+		return "UTM:" + m[1] + strings.ToUpper(m[2]), nil
+	}
+
+	projection := root.child("PROJECTION").text(0)
+	switch strings.ToLower(projection) {
+	case "transverse_mercator", "gauss_kruger":
+	default:
+		return "", fmt.Errorf("unsupported projection: %q", projection)
+	}
+	if unit := root.child("UNIT"); unit != nil {
+		if factor, ok := unit.number(1); ok && factor != 1 {
+			return "", fmt.Errorf("unsupported unit: %q", unit.text(0))
+		}
+	}
+
+	ellps := "WGS84"
+	if geogcs := root.child("GEOGCS"); geogcs != nil {
+		spheroid := strings.ToUpper(geogcs.child("DATUM").child("SPHEROID").text(0))
+		switch {
+		case strings.Contains(spheroid, "GRS") && strings.Contains(spheroid, "80"):
+			ellps = "GRS80"
+		case strings.Contains(spheroid, "WGS") && strings.Contains(spheroid, "84"):
+		default:
+			return "", fmt.Errorf("unsupported spheroid: %q", spheroid)
+		}
+	}
+
+	params := map[string]float64{}
+	for _, arg := range root.Args {
+		if node, ok := arg.(*wktNode); ok && strings.EqualFold(node.Name, "PARAMETER") {
+			if v, ok := node.number(1); ok {
+				params[strings.ToLower(node.text(0))] = v
+			}
+		}
+	}
+	scale, ok := params["scale_factor"]
+	if !ok {
+		scale = 1
+	}
+	return fmt.Sprintf("+proj=tmerc +lat_0=%g +lon_0=%g +k=%g +x_0=%g +y_0=%g +ellps=%s",
+		params["latitude_of_origin"], params["central_meridian"], scale,
+		params["false_easting"], params["false_northing"], ellps), nil
+}
+
+// 経度緯度のCRSの名前（測地系がJGD2011なら "JGD2011"、それ以外は "WGS84"）
+func geogcsName(geogcs *wktNode) string {
+	datum := strings.ToUpper(strings.ReplaceAll(geogcs.child("DATUM").text(0), "_", ""))
+	if strings.Contains(datum, "JGD2011") || strings.Contains(datum, "JAPANESEGEODETICDATUM2011") {
+		return "JGD2011"
+	}
+	return "WGS84"
+}
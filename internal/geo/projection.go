@@ -0,0 +1,96 @@
+/*
+# projection.go
+
+表示用の地図投影法
+*/
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// WGS84の長半径（m）。Web Mercatorと正距円筒図法の球の半径に使う
+	earthRadius = 6378137.0
+	// Web Mercatorで表示できる緯度の上限
+	maxMercatorLat = 85.05112877980659
+	// 極付近でcos(緯度)が0になって経度方向が潰れるのを防ぐ下限
+	minLonScale = 0.01
+)
+
+// Projection は経度緯度と投影座標を相互に変換する
+type Projection interface {
+	// 経度緯度を投影座標に変換する
+	Forward(lon, lat float64) (x, y float64)
+	// 投影座標を経度緯度に変換する
+	Inverse(x, y float64) (lon, lat float64)
+}
+
+// 表示用の投影法の名前（TUIで切り替える順）
+var ProjectionNames = []string{"equirectangular", "mercator", "platecarree"}
+
+// NewProjection は名前から表示用の投影法を作る。
+// 正距円筒図法の標準緯線にはboundsの中心緯度を使う。
+func NewProjection(name string, bounds Bound) (Projection, error) {
+	switch strings.TrimSpace(name) {
+	case "", "equirectangular":
+		_, lat0 := bounds.Center()
+		return Equirectangular{Lat0: lat0}, nil
+	case "mercator":
+		return WebMercator{}, nil
+	case "platecarree":
+		return PlateCarree{}, nil
+	}
+	return nil, fmt.Errorf("unknown projection: %q", name)
+}
+
+// WebMercator はEPSG:3857（球面メルカトル、単位m）
+type WebMercator struct{}
+
+func (WebMercator) Forward(lon, lat float64) (float64, float64) {
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	x := earthRadius * lon * math.Pi / 180
+	y := earthRadius * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+	return x, y
+}
+
+func (WebMercator) Inverse(x, y float64) (float64, float64) {
+	lon := x / earthRadius * 180 / math.Pi
+	lat := (2*math.Atan(math.Exp(y/earthRadius)) - math.Pi/2) * 180 / math.Pi
+	return lon, lat
+}
+
+// Equirectangular は標準緯線Lat0の正距円筒図法（単位m）
+// 経度方向をcos(Lat0)倍し、標準緯線付近で形が正しくなる
+type Equirectangular struct {
+	Lat0 float64
+}
+
+func (p Equirectangular) lonScale() float64 {
+	return math.Max(math.Cos(p.Lat0*math.Pi/180), minLonScale)
+}
+
+func (p Equirectangular) Forward(lon, lat float64) (float64, float64) {
+	x := earthRadius * lon * math.Pi / 180 * p.lonScale()
+	y := earthRadius * lat * math.Pi / 180
+	return x, y
+}
+
+func (p Equirectangular) Inverse(x, y float64) (float64, float64) {
+	lon := x / (earthRadius * p.lonScale()) * 180 / math.Pi
+	lat := y / earthRadius * 180 / math.Pi
+	return lon, lat
+}
+
+// PlateCarree は経度緯度をそのまま平面座標とみなす（単位は度）
+type PlateCarree struct{}
+
+func (PlateCarree) Forward(lon, lat float64) (float64, float64) {
+	return lon, lat
+}
+
+func (PlateCarree) Inverse(x, y float64) (float64, float64) {
+	return x, y
+}
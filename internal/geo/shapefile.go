@@ -0,0 +1,504 @@
+/*
+# shapefile.go
+
+ESRIシェープファイル（.shp・.shx・.dbf・.prj）を読み込み、Layer型に変換するモジュール
+zipにまとめられたシェープファイルも読める
+*/
+package geo
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// シェープの種類（ESRI Shapefile Technical Description）
+const (
+	shapeNull        = 0
+	shapePoint       = 1
+	shapePolyLine    = 3
+	shapePolygon     = 5
+	shapeMultiPoint  = 8
+	shapePointZ      = 11
+	shapePolyLineZ   = 13
+	shapePolygonZ    = 15
+	shapeMultiPointZ = 18
+	shapePointM      = 21
+	shapePolyLineM   = 23
+	shapePolygonM    = 25
+	shapeMultiPointM = 28
+	shapeMultiPatch  = 31
+
+	// .shp・.shx のファイルコード
+	shapeFileCode = 9994
+	// .shp・.shx のヘッダーの長さ（バイト）
+	shapeHeaderSize = 100
+	// これより小さいM値は「値なし」
+	shapeNoData = -1e38
+)
+
+// シェープファイルを構成するファイルの中身。.shp以外は無ければnil
+type shapefileFiles struct {
+	shp, shx, dbf, prj, cpg []byte
+}
+
+/*
+ReadShapefile
+シェープファイルを読み込み、地理座標をLayer型で返す。
+
+.shpと同じ名前の .shx（レコードの位置）・.dbf（属性）・.prj（CRS）・.cpg（文字コード）があれば使う。
+属性は CachedFeature.Properties に入り、.prj のCRSは Layer.CRSName になる。
+診断の位置は "/records/3"（4番目のレコード）の形式になる。
+
+Args:
+
+	path: .shp ファイルのパス
+
+Returns:
+
+	Layer
+*/
+func ReadShapefile(path string) (Layer, error) {
+	shp, err := os.ReadFile(path)
+	if err != nil {
+		return Layer{Valid: false}, fmt.Errorf("read file: %w", err)
+	}
+	files := shapefileFiles{shp: shp}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for ext, target := range map[string]*[]byte{".shx": &files.shx, ".dbf": &files.dbf, ".prj": &files.prj, ".cpg": &files.cpg} {
+		*target = readSidecar(base, ext)
+	}
+	return readShapefile(files)
+}
+
+// base + ext（拡張子の大文字・小文字は問わない）を読む。無ければnil
+func readSidecar(base, ext string) []byte {
+	for _, candidate := range []string{base + ext, base + strings.ToUpper(ext)} {
+		if data, err := os.ReadFile(candidate); err == nil {
+			return data
+		}
+	}
+	return nil
+}
+
+/*
+ReadShapefileZip
+zipに入ったシェープファイルを読み込み、地理座標をLayer型で返す。
+zipの中の最初（パス順）の .shp と、同じ名前の .shx・.dbf・.prj・.cpg を使う。
+.shp が複数あれば、読まなかったものを警告に記録する。
+*/
+func ReadShapefileZip(path string) (Layer, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return Layer{Valid: false}, fmt.Errorf("open zip: %w", err)
+	}
+	defer archive.Close()
+
+	entries := map[string]*zip.File{}
+	var shpNames []string
+	for _, file := range archive.File {
+		name := strings.ToLower(file.Name)
+		// macOSのzipに入る "__MACOSX/._foo.shp" のようなメタデータは除く
+		if strings.HasPrefix(name, "__macosx/") || strings.HasPrefix(filepath.Base(name), "._") {
+			continue
+		}
+		entries[name] = file
+		if strings.HasSuffix(name, ".shp") {
+			shpNames = append(shpNames, name)
+		}
+	}
+	if len(shpNames) == 0 {
+		return Layer{Valid: false}, errors.New("no .shp file in zip")
+	}
+	sort.Strings(shpNames)
+
+	read := func(name string) ([]byte, error) {
+		file, ok := entries[name]
+		if !ok {
+			return nil, nil
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", file.Name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", file.Name, err)
+		}
+		return data, nil
+	}
+	base := strings.TrimSuffix(shpNames[0], ".shp")
+	var files shapefileFiles
+	for ext, target := range map[string]*[]byte{".shp": &files.shp, ".shx": &files.shx, ".dbf": &files.dbf, ".prj": &files.prj, ".cpg": &files.cpg} {
+		if *target, err = read(base + ext); err != nil {
+			return Layer{Valid: false}, err
+		}
+	}
+	layer, err := readShapefile(files)
+	for _, name := range shpNames[1:] {
+		layer.Diagnostics.warnf("", "zip also contains %s; only %s was read", entries[name].Name, entries[shpNames[0]].Name)
+	}
+	return layer, err
+}
+
+// シェープファイルの中身をLayerに変換する
+func readShapefile(files shapefileFiles) (Layer, error) {
+	shp := files.shp
+	if len(shp) < shapeHeaderSize || binary.BigEndian.Uint32(shp[0:4]) != shapeFileCode {
+		return Layer{Valid: false}, errors.New("not a shapefile (.shp header is missing)")
+	}
+
+	builder := newLayerBuilder()
+	table, err := readDBF(files.dbf, files.cpg, &builder.diags)
+	if err != nil {
+		builder.diags.errorf("/dbf", "ignoring attributes: %v", err)
+		table = nil
+	}
+
+	records := shapeRecords(shp, files.shx, &builder.diags)
+	if table != nil && len(table.records) != len(records) {
+		builder.diags.warnf("/dbf", "%d attribute records for %d shapes", len(table.records), len(records))
+	}
+	for i, record := range records {
+		pointer := pointerIndex("/records", i)
+		feature := Feature{Valid: true, Properties: map[string]interface{}{}}
+		if table != nil && i < len(table.records) {
+			feature.Properties = table.records[i]
+		}
+		geometry, err := decodeShape(record)
+		if err != nil {
+			feature.Diagnostics.errorf("", "%v; skipped", err)
+		} else if geometry == nil {
+			feature.Diagnostics.warnf("", "record has no shape; skipped")
+		} else {
+			feature.Geometry = geometry
+		}
+		builder.addFeature(feature, pointer)
+	}
+
+	layer, err := builder.finish(nil)
+	if err != nil {
+		return layer, err
+	}
+	if len(files.prj) > 0 {
+		if name, err := prjCRSName(string(files.prj)); err != nil {
+			layer.Diagnostics.warnf("/prj", "ignoring .prj: %v; reading as WGS84", err)
+		} else {
+			layer.CRSName = name
+		}
+	}
+	return layer, nil
+}
+
+/*
+各レコードの内容（シェープの種類から始まるバイト列）を返す。
+.shx があればそこに書かれた位置を使い、無い・壊れていれば .shp を先頭から順に読む。
+*/
+func shapeRecords(shp, shx []byte, diags *Diagnostics) [][]byte {
+	if len(shx) >= shapeHeaderSize && binary.BigEndian.Uint32(shx[0:4]) == shapeFileCode {
+		var records [][]byte
+		ok := true
+		for pos := shapeHeaderSize; pos+8 <= len(shx); pos += 8 {
+			offset := int(binary.BigEndian.Uint32(shx[pos:])) * 2
+			length := int(binary.BigEndian.Uint32(shx[pos+4:])) * 2
+			if offset+8+length > len(shp) {
+				ok = false
+				break
+			}
+			records = append(records, shp[offset+8:offset+8+length])
+		}
+		if ok {
+			return records
+		}
+		diags.warnf("/shx", ".shx points past the end of .shp; reading records in order")
+	}
+
+	var records [][]byte
+	fileLength := int(binary.BigEndian.Uint32(shp[24:28])) * 2
+	if fileLength > len(shp) || fileLength < shapeHeaderSize {
+		fileLength = len(shp)
+	}
+	for pos := shapeHeaderSize; pos+8 <= fileLength; {
+		length := int(binary.BigEndian.Uint32(shp[pos+4:])) * 2
+		if pos+8+length > fileLength {
+			diags.errorf(pointerIndex("/records", len(records)), "record is truncated; skipped the rest of the file")
+			break
+		}
+		records = append(records, shp[pos+8:pos+8+length])
+		pos += 8 + length
+	}
+	return records
+}
+
+// リトルエンディアンの値を順に読む。範囲外を読むとokがfalseになる
+type shapeReader struct {
+	data []byte
+	pos  int
+	ok   bool
+}
+
+func (r *shapeReader) int32() int {
+	if r.pos+4 > len(r.data) {
+		r.ok = false
+		return 0
+	}
+	v := int32(binary.LittleEndian.Uint32(r.data[r.pos:]))
+	r.pos += 4
+	return int(v)
+}
+
+func (r *shapeReader) float64() float64 {
+	if r.pos+8 > len(r.data) {
+		r.ok = false
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+	r.pos += 8
+	return v
+}
+
+// n個の値を読む。レコードの残りより多ければ読まずにokをfalseにする
+func (r *shapeReader) float64s(n int) []float64 {
+	if n < 0 || r.pos+8*n > len(r.data) {
+		r.ok = false
+		return nil
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = r.float64()
+	}
+	return values
+}
+
+func (r *shapeReader) skip(n int) {
+	r.pos += n
+}
+
+// 残りのバイトがあればtrue（Z・M付きの種類でMが省略されているかの判定に使う）
+func (r *shapeReader) more() bool {
+	return r.pos < len(r.data)
+}
+
+// M値の「値なし」をNaNにする
+func shapeMeasure(v float64) float64 {
+	if v < shapeNoData {
+		return math.NaN()
+	}
+	return v
+}
+
+/*
+decodeShape
+レコード1つ分のシェープをジオメトリに変換する。Nullシェープならnilを返す。
+PolyLineはライン、Polygonは外周（時計回り）と穴（反時計回り）の組、
+Point・MultiPointは頂点1つの外周リングになる。Z・Mは PathMeasures に保持する。
+*/
+func decodeShape(record []byte) (*Geometry, error) {
+	r := &shapeReader{data: record, ok: true}
+	shapeType := r.int32()
+	if !r.ok {
+		return nil, errors.New("record is empty")
+	}
+	geometry := &Geometry{Bounds: emptyBound()}
+	hasZ, hasM := false, false
+	switch shapeType {
+	case shapeNull:
+		return nil, nil
+	case shapePoint, shapePointZ, shapePointM:
+		x, y := r.float64(), r.float64()
+		z, m := math.NaN(), math.NaN()
+		switch shapeType {
+		case shapePointZ:
+			z = r.float64()
+			if r.more() {
+				m = shapeMeasure(r.float64())
+			}
+		case shapePointM:
+			m = shapeMeasure(r.float64())
+		}
+		if !r.ok {
+			return nil, errors.New("point record is truncated")
+		}
+		if !isFinite(x) || !isFinite(y) {
+			return nil, errors.New("point is not a pair of finite numbers")
+		}
+		geometry.Type = GeometryPoint
+		geometry.Parts = []CachedPart{{Exterior: [][2]float64{{x, y}}, Measures: pointMeasures(z, m)}}
+		geometry.Bounds.extend(x, y)
+		return geometry, nil
+	case shapeMultiPoint, shapePolyLine, shapePolygon:
+	case shapeMultiPointZ, shapePolyLineZ, shapePolygonZ:
+		hasZ, hasM = true, true
+	case shapeMultiPointM, shapePolyLineM, shapePolygonM:
+		hasM = true
+	case shapeMultiPatch:
+		return nil, errors.New("unsupported shape type MultiPatch")
+	default:
+		return nil, fmt.Errorf("unknown shape type %d", shapeType)
+	}
+
+	r.skip(32) // bbox
+	multiPoint := shapeType == shapeMultiPoint || shapeType == shapeMultiPointZ || shapeType == shapeMultiPointM
+	numParts := 1
+	if !multiPoint {
+		numParts = r.int32()
+	}
+	numPoints := r.int32()
+	if !r.ok || numParts < 0 || numPoints < 0 || numParts > numPoints && numPoints > 0 {
+		return nil, errors.New("record header is invalid")
+	}
+	// 壊れた数で巨大なスライスを割り当てないよう、部分の開始位置と座標がレコードに収まるかを先に確かめる
+	if need := 4*int64(numParts)*int64(btoi(!multiPoint)) + 16*int64(numPoints); need > int64(len(r.data)-r.pos) {
+		return nil, fmt.Errorf("record is truncated: %d parts and %d points need %d bytes, %d left", numParts, numPoints, need, len(r.data)-r.pos)
+	}
+	starts := []int{0}
+	if !multiPoint {
+		starts = make([]int, numParts)
+		for i := range starts {
+			starts[i] = r.int32()
+		}
+	}
+	xy := r.float64s(2 * numPoints)
+	var zs, ms []float64
+	if hasZ {
+		r.skip(16) // Zの範囲
+		zs = r.float64s(numPoints)
+	}
+	// Z付きの種類ではMは省略できる
+	if hasM && r.ok && r.more() {
+		r.skip(16) // Mの範囲
+		ms = r.float64s(numPoints)
+		for i, v := range ms {
+			ms[i] = shapeMeasure(v)
+		}
+	}
+	if !r.ok {
+		return nil, errors.New("record is truncated")
+	}
+
+	point := func(i int) [2]float64 {
+		return [2]float64{xy[2*i], xy[2*i+1]}
+	}
+	for i := 0; i < numPoints; i++ {
+		if !isFinite(xy[2*i]) || !isFinite(xy[2*i+1]) {
+			return nil, fmt.Errorf("point %d is not a pair of finite numbers", i)
+		}
+		geometry.Bounds.extend(xy[2*i], xy[2*i+1])
+	}
+	// i番目からj番目の手前までの頂点のZ・M
+	measures := func(i, j int) PathMeasures {
+		var out PathMeasures
+		for k := i; k < j; k++ {
+			if zs != nil {
+				out.Z = appendMeasure(out.Z, k-i, zs[k])
+			}
+			if ms != nil {
+				out.M = appendMeasure(out.M, k-i, ms[k])
+			}
+		}
+		return out
+	}
+
+	if multiPoint {
+		geometry.Type = GeometryMultiPoint
+		for i := 0; i < numPoints; i++ {
+			m := measures(i, i+1)
+			geometry.Parts = append(geometry.Parts, CachedPart{Exterior: [][2]float64{point(i)}, Measures: appendPathMeasures(nil, 0, m)})
+		}
+		return geometry, nil
+	}
+
+	// パートごとに頂点を切り出す
+	var paths [][][2]float64
+	var pathMeasures []PathMeasures
+	for p := 0; p < numParts; p++ {
+		start, end := starts[p], numPoints
+		if p+1 < numParts {
+			end = starts[p+1]
+		}
+		if start < 0 || start >= end || end > numPoints {
+			return nil, fmt.Errorf("part %d has invalid point indexes", p)
+		}
+		path := make([][2]float64, 0, end-start)
+		for i := start; i < end; i++ {
+			path = append(path, point(i))
+		}
+		paths = append(paths, path)
+		pathMeasures = append(pathMeasures, measures(start, end))
+	}
+
+	if shapeType == shapePolyLine || shapeType == shapePolyLineZ || shapeType == shapePolyLineM {
+		geometry.Type = GeometryMultiLineString
+		if len(paths) == 1 {
+			geometry.Type = GeometryLineString
+		}
+		geometry.Lines = paths
+		for i, m := range pathMeasures {
+			geometry.LineMeasures = appendPathMeasures(geometry.LineMeasures, i, m)
+		}
+		return geometry, nil
+	}
+
+	geometry.Parts = assembleShapeRings(paths, pathMeasures)
+	geometry.Type = GeometryMultiPolygon
+	if len(geometry.Parts) == 1 {
+		geometry.Type = GeometryPolygon
+	}
+	return geometry, nil
+}
+
+/*
+Polygonのリングを外周と穴の組にまとめる。
+シェープファイルでは外周が時計回り、穴が反時計回りなので符号付き面積で見分け、
+穴はそれを含む外周（最初の頂点が内側にあるもの）に割り当てる。含む外周が無い穴は外周として扱う。
+*/
+func assembleShapeRings(rings [][][2]float64, measures []PathMeasures) []CachedPart {
+	var parts []CachedPart
+	type hole struct {
+		ring     [][2]float64
+		measures PathMeasures
+	}
+	var holes []hole
+	for i, ring := range rings {
+		if ringArea(ring) > 0 {
+			holes = append(holes, hole{ring, measures[i]})
+			continue
+		}
+		parts = append(parts, CachedPart{Exterior: ring, Measures: appendPathMeasures(nil, 0, measures[i])})
+	}
+	for _, h := range holes {
+		owner := -1
+		for i, part := range parts {
+			if ringContains(part.Exterior, h.ring[0]) {
+				owner = i
+				break
+			}
+		}
+		if owner < 0 {
+			parts = append(parts, CachedPart{Exterior: h.ring, Measures: appendPathMeasures(nil, 0, h.measures)})
+			continue
+		}
+		part := &parts[owner]
+		part.Measures = appendPathMeasures(part.Measures, len(part.Holes)+1, h.measures)
+		part.Holes = append(part.Holes, h.ring)
+	}
+	return parts
+}
+
+// リングの符号付き面積（反時計回りが正）
+func ringArea(ring [][2]float64) float64 {
+	area := 0.0
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return area / 2
+}
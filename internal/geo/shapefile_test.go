@@ -0,0 +1,212 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// リトルエンディアンでレコードの内容（シェープの種類から）を組み立てる
+func shapeContent(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		switch v := v.(type) {
+		case int:
+			_ = binary.Write(&buf, binary.LittleEndian, int32(v))
+		case float64:
+			_ = binary.Write(&buf, binary.LittleEndian, math.Float64bits(v))
+		}
+	}
+	return buf.Bytes()
+}
+
+func pointShape(x, y float64) []byte {
+	return shapeContent(shapePoint, x, y)
+}
+
+// PolyLine・Polygonのレコード（bboxは読まれないので0のまま）
+func multiPartShape(shapeType int, parts ...[][2]float64) []byte {
+	points := 0
+	for _, part := range parts {
+		points += len(part)
+	}
+	values := []interface{}{shapeType, 0.0, 0.0, 0.0, 0.0, len(parts), points}
+	start := 0
+	for _, part := range parts {
+		values = append(values, start)
+		start += len(part)
+	}
+	for _, part := range parts {
+		for _, p := range part {
+			values = append(values, p[0], p[1])
+		}
+	}
+	return shapeContent(values...)
+}
+
+// .shp と .shx を組み立てる
+func shapeFiles(records ...[]byte) (shp, shx []byte) {
+	header := func(length int) []byte {
+		h := make([]byte, shapeHeaderSize)
+		binary.BigEndian.PutUint32(h[0:], shapeFileCode)
+		binary.BigEndian.PutUint32(h[24:], uint32(length/2))
+		binary.LittleEndian.PutUint32(h[28:], 1000)
+		return h
+	}
+	var body, index []byte
+	for i, record := range records {
+		offset := shapeHeaderSize + len(body)
+		entry := make([]byte, 8)
+		binary.BigEndian.PutUint32(entry[0:], uint32(offset/2))
+		binary.BigEndian.PutUint32(entry[4:], uint32(len(record)/2))
+		index = append(index, entry...)
+		recordHeader := make([]byte, 8)
+		binary.BigEndian.PutUint32(recordHeader[0:], uint32(i+1))
+		binary.BigEndian.PutUint32(recordHeader[4:], uint32(len(record)/2))
+		body = append(append(body, recordHeader...), record...)
+	}
+	shp = append(header(shapeHeaderSize+len(body)), body...)
+	shx = append(header(shapeHeaderSize+len(index)), index...)
+	return shp, shx
+}
+
+var (
+	// 時計回りの外周と反時計回りの穴
+	shapeSquare = [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}
+	shapeHole   = [][2]float64{{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}}
+	shapeLine   = [][2]float64{{20, 20}, {21, 22}, {23, 21}}
+)
+
+func testShapeRecords() [][]byte {
+	return [][]byte{
+		pointShape(139.7, 35.6),
+		multiPartShape(shapePolyLine, shapeLine),
+		multiPartShape(shapePolygon, shapeSquare, shapeHole),
+	}
+}
+
+func checkShapeFeatures(t *testing.T, features []CachedFeature) {
+	t.Helper()
+	if len(features) != 3 {
+		t.Fatalf("%d features, want 3", len(features))
+	}
+	if got := features[0].Parts; len(got) != 1 || !reflect.DeepEqual(got[0].Exterior, [][2]float64{{139.7, 35.6}}) {
+		t.Errorf("point = %v", got)
+	}
+	if got := features[1].Lines; len(got) != 1 || !reflect.DeepEqual(got[0], shapeLine) {
+		t.Errorf("line = %v", got)
+	}
+	polygon := features[2].Parts
+	if len(polygon) != 1 || !reflect.DeepEqual(polygon[0].Exterior, shapeSquare) ||
+		len(polygon[0].Holes) != 1 || !reflect.DeepEqual(polygon[0].Holes[0], shapeHole) {
+		t.Errorf("polygon = %v, want the square with one hole", polygon)
+	}
+}
+
+func TestReadShapefileRecords(t *testing.T) {
+	shp, shx := shapeFiles(testShapeRecords()...)
+	layer, err := readShapefile(shapefileFiles{shp: shp, shx: shx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkShapeFeatures(t, layer.Features)
+	if len(layer.Diagnostics) != 0 {
+		t.Errorf("diagnostics = %v", layer.Diagnostics)
+	}
+}
+
+func TestReadShapefileWithoutShx(t *testing.T) {
+	shp, _ := shapeFiles(testShapeRecords()...)
+	layer, err := readShapefile(shapefileFiles{shp: shp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkShapeFeatures(t, layer.Features)
+}
+
+func TestReadShapefileBrokenShx(t *testing.T) {
+	shp, shx := shapeFiles(testShapeRecords()...)
+	// 最後のレコードの位置を .shp の外にする
+	binary.BigEndian.PutUint32(shx[len(shx)-8:], uint32(len(shp)))
+	layer, err := readShapefile(shapefileFiles{shp: shp, shx: shx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkShapeFeatures(t, layer.Features)
+	if !hasDiagnostic(layer.Diagnostics, SeverityWarning, "/shx", "reading records in order") {
+		t.Errorf("diagnostics = %v, want the .shx fallback", layer.Diagnostics)
+	}
+}
+
+func TestReadShapefileTruncated(t *testing.T) {
+	records := testShapeRecords()
+	shp, _ := shapeFiles(records...)
+	// 最後のレコードの途中でファイルが切れている
+	cut := shp[:len(shp)-20]
+	layer, err := readShapefile(shapefileFiles{shp: cut})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 2 {
+		t.Errorf("%d features, want the 2 complete records", len(layer.Features))
+	}
+	if !hasDiagnostic(layer.Diagnostics, SeverityError, "/records/2", "truncated") {
+		t.Errorf("diagnostics = %v, want the truncated record", layer.Diagnostics)
+	}
+
+	// レコードの長さは正しいが、頂点の数が内容より多い
+	short := multiPartShape(shapePolyLine, shapeLine)
+	binary.LittleEndian.PutUint32(short[40:], 100)
+	shp, shx := shapeFiles(pointShape(1, 2), short)
+	layer, err = readShapefile(shapefileFiles{shp: shp, shx: shx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || layer.Skipped != 1 {
+		t.Errorf("%d features, %d skipped, want 1 and 1", len(layer.Features), layer.Skipped)
+	}
+	if !hasDiagnostic(layer.Diagnostics, SeverityError, "/records/1", "truncated") {
+		t.Errorf("diagnostics = %v, want the short record", layer.Diagnostics)
+	}
+}
+
+func TestReadShapefileHugeCounts(t *testing.T) {
+	// bboxまでの44バイトだけで、部分と頂点の数が巨大なPolyLine
+	header := shapeContent(shapePolyLine, 0.0, 0.0, 0.0, 0.0, math.MaxInt32, math.MaxInt32)
+	shp, shx := shapeFiles(pointShape(1, 2), header)
+	layer, err := readShapefile(shapefileFiles{shp: shp, shx: shx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || layer.Skipped != 1 {
+		t.Errorf("%d features, %d skipped, want 1 and 1", len(layer.Features), layer.Skipped)
+	}
+	if !hasDiagnostic(layer.Diagnostics, SeverityError, "/records/1", "truncated") {
+		t.Errorf("diagnostics = %v, want the oversized counts", layer.Diagnostics)
+	}
+}
+
+func TestReadShapefileNonFinite(t *testing.T) {
+	line := [][2]float64{{0, 0}, {math.NaN(), 1}}
+	shp, shx := shapeFiles(pointShape(1, 2), multiPartShape(shapePolyLine, line), pointShape(math.Inf(1), 0))
+	layer, err := readShapefile(shapefileFiles{shp: shp, shx: shx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || layer.Bounds != (Bound{LonMin: 1, LonMax: 1, LatMin: 2, LatMax: 2}) {
+		t.Errorf("%d features with bounds %+v, want only the finite point", len(layer.Features), layer.Bounds)
+	}
+	for _, pointer := range []string{"/records/1", "/records/2"} {
+		if !hasDiagnostic(layer.Diagnostics, SeverityError, pointer, "finite") {
+			t.Errorf("diagnostics = %v, want an error at %s", layer.Diagnostics, pointer)
+		}
+	}
+}
+
+func TestReadShapefileNotAShapefile(t *testing.T) {
+	if _, err := readShapefile(shapefileFiles{shp: []byte("not a shapefile")}); err == nil {
+		t.Error("readShapefile succeeded, want error")
+	}
+}
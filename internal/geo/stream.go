@@ -0,0 +1,149 @@
+/*
+# stream.go
+
+GeoJSONをトークン単位で読み、featuresを1つずつLayerに変換するモジュール
+ファイル全体を map[string]interface{} に展開しないため、巨大なFeatureCollectionも読み込める
+*/
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// progressInterval フィーチャーいくつごとに進捗を通知するか
+const progressInterval = 1000
+
+// 読み込みの進捗
+type Progress struct {
+	// 読み終えたバイト数
+	Bytes int64
+	// 読み終えたフィーチャー数（読み飛ばしたものを含む）
+	Features int
+}
+
+/*
+ReadLayer
+GeoJSONをストリームで読み込み、地理座標をLayer型で返す。
+
+FeatureCollectionのfeaturesは1つずつ Feature にデコードしてその場で CachedFeature に変換し、
+境界ボックスも同時に広げていく。features以外のメンバー（type・crs・bboxなど）は
+ルートを読み終えてから解釈する。ルートは単独のFeatureやジオメトリでもよい
+（ルートのGeometryCollectionはメンバーごとに別のフィーチャーになる）。
+TopoJSONのTopologyも受け付け、全オブジェクトを1つのレイヤーにまとめる（オブジェクトごとは ReadTopoJSON）。
+
+Args:
+
+	r: GeoJSONの入力
+	progress: 進捗の通知先（nilなら通知しない）。progressInterval フィーチャーごとと読み終えたときに呼ばれる
+
+Returns:
+
+	Layer
+*/
+func ReadLayer(r io.Reader, progress func(Progress)) (Layer, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return Layer{Valid: false}, errors.New("empty GeoJSON")
+	}
+	if err != nil {
+		return Layer{Valid: false}, fmt.Errorf("parse JSON: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return Layer{Valid: false}, errors.New("GeoJSON root is not an object")
+	}
+
+	builder := newLayerBuilder()
+	root := map[string]json.RawMessage{}
+	streamed := false
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return Layer{Valid: false}, fmt.Errorf("parse JSON: %w", err)
+		}
+		key, _ := keyTok.(string)
+		if key == "features" {
+			if err := builder.streamFeatures(dec, progress); err != nil {
+				return Layer{Valid: false}, err
+			}
+			streamed = true
+			continue
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return Layer{Valid: false}, fmt.Errorf("parse JSON: %w", err)
+		}
+		root[key] = value
+	}
+	// ルートの閉じ括弧と、その後に余計なデータが無いことを確かめる
+	if _, err := dec.Token(); err != nil {
+		return Layer{Valid: false}, fmt.Errorf("parse JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return Layer{Valid: false}, errors.New("parse JSON: unexpected data after the root object")
+	}
+	if progress != nil {
+		progress(Progress{Bytes: dec.InputOffset(), Features: len(builder.features) + builder.skipped})
+	}
+
+	return builder.finishRoot(root, streamed)
+}
+
+// ルートのtypeに応じてフィーチャーを追加し、Layerを返す。streamed はfeaturesを読み終えていればtrue
+func (b *layerBuilder) finishRoot(root map[string]json.RawMessage, streamed bool) (Layer, error) {
+	var rootType string
+	if raw, ok := root["type"]; ok {
+		_ = json.Unmarshal(raw, &rootType)
+	}
+	switch {
+	case streamed && (rootType == "" || rootType == "FeatureCollection"):
+	case rootType == "Feature":
+		// ルートのbboxはレイヤーの範囲として finish で扱う
+		members := make(map[string]json.RawMessage, len(root))
+		for key, value := range root {
+			if key != "bbox" {
+				members[key] = value
+			}
+		}
+		b.addFeature(featureFromMembers(members), "")
+	case rootType == "Topology":
+		// TopoJSON は全オブジェクトを1つのレイヤーにまとめる（ReadTopoJSON を参照）
+		return readTopology(root)
+	case GeometryType(rootType).Valid():
+		b.addGeometry(geometryFromMembers(root), "")
+	case rootType != "" && rootType != "FeatureCollection":
+		return Layer{Valid: false}, fmt.Errorf("unsupported GeoJSON type: %q", rootType)
+	default:
+		return Layer{Valid: false}, errors.New("features not found in GeoJSON")
+	}
+	return b.finish(root)
+}
+
+// features配列を1要素ずつデコードしてLayerに追加する
+func (b *layerBuilder) streamFeatures(dec *json.Decoder, progress func(Progress)) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("parse JSON: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return errors.New("features is not a slice")
+	}
+	for i := 0; dec.More(); i++ {
+		var feature Feature
+		if err := dec.Decode(&feature); err != nil {
+			return fmt.Errorf("parse JSON: %w", err)
+		}
+		b.addFeature(feature, pointerIndex("/features", i))
+		if progress != nil && (i+1)%progressInterval == 0 {
+			progress(Progress{Bytes: dec.InputOffset(), Features: i + 1})
+		}
+	}
+	// 配列の閉じ括弧
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("parse JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// ベンチマーク用の大きなFeatureCollection（ポリゴン・ライン・点を交互に並べる）
+func largeFeatureCollection(features int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":"FeatureCollection","features":[`)
+	for i := 0; i < features; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		lon, lat := 120+float64(i%200)*0.1, 20+float64(i/200%200)*0.1
+		fmt.Fprintf(&buf, `{"type":"Feature","properties":{"name":"f%d","id":%d},"geometry":`, i, i)
+		switch i % 3 {
+		case 0:
+			buf.WriteString(`{"type":"Polygon","coordinates":[[`)
+			for j := 0; j <= 32; j++ {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(&buf, `[%.6f,%.6f]`, lon+0.05*float64(j%2), lat+0.05*float64(j)/32)
+			}
+			fmt.Fprintf(&buf, `,[%.6f,%.6f]]]}`, lon, lat)
+		case 1:
+			buf.WriteString(`{"type":"LineString","coordinates":[`)
+			for j := 0; j < 32; j++ {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(&buf, `[%.6f,%.6f,%d]`, lon+0.002*float64(j), lat+0.001*float64(j), j)
+			}
+			buf.WriteString(`]}`)
+		default:
+			fmt.Fprintf(&buf, `{"type":"Point","coordinates":[%.6f,%.6f]}`, lon, lat)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+const benchmarkFeatures = 10000
+
+func BenchmarkReadLayer(b *testing.B) {
+	data := largeFeatureCollection(benchmarkFeatures)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		layer, err := ReadLayer(bytes.NewReader(data), nil)
+		if err != nil || len(layer.Features) != benchmarkFeatures {
+			b.Fatalf("ReadLayer: %d features, %v", len(layer.Features), err)
+		}
+	}
+}
+
+// 比較用: 型付きモデル導入前の読み方（全体を map[string]interface{} に展開してから座標を拾う）
+func BenchmarkReadLayerMapDecode(b *testing.B) {
+	data := largeFeatureCollection(benchmarkFeatures)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var root map[string]interface{}
+		if err := json.Unmarshal(data, &root); err != nil {
+			b.Fatal(err)
+		}
+		features, _ := root["features"].([]interface{})
+		cached := make([]CachedFeature, 0, len(features))
+		bound := emptyBound()
+		var diags Diagnostics
+		for _, value := range features {
+			feature, _ := value.(map[string]interface{})
+			geometry, _ := feature["geometry"].(map[string]interface{})
+			typeName, _ := geometry["type"].(string)
+			parts, lines, lineMeasures := parseCoordinates(GeometryType(typeName), geometry["coordinates"], "/coordinates", &diags)
+			extendBound(&bound, parts, lines)
+			properties, _ := feature["properties"].(map[string]interface{})
+			name, _ := properties["name"].(string)
+			cached = append(cached, CachedFeature{Name: name, Properties: properties, Parts: parts, Lines: lines, LineMeasures: lineMeasures})
+		}
+		if len(cached) != benchmarkFeatures {
+			b.Fatalf("%d features", len(cached))
+		}
+	}
+}
+
+func TestReadLayerLargeFixture(t *testing.T) {
+	layer, err := ReadLayer(bytes.NewReader(largeFeatureCollection(300)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 300 || len(layer.Diagnostics) != 0 {
+		t.Fatalf("%d features, diagnostics %v", len(layer.Features), layer.Diagnostics)
+	}
+	if layer.Elevation == nil || layer.Elevation.Min != 0 || layer.Elevation.Max != 31 {
+		t.Errorf("Elevation = %+v, want 0..31", layer.Elevation)
+	}
+	if !strings.HasPrefix(layer.Features[0].Name, "f0") {
+		t.Errorf("first feature name = %q", layer.Features[0].Name)
+	}
+}
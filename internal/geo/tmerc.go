@@ -0,0 +1,145 @@
+/*
+# tmerc.go
+
+横メルカトル図法（Gauss-Krüger）の順変換・逆変換
+国土地理院「平面直角座標への換算」と同じKrüger級数（n^5まで）を使う
+*/
+package geo
+
+import "math"
+
+// Ellipsoid は回転楕円体
+type Ellipsoid struct {
+	// 長半径（m）
+	A float64
+	// 扁平率
+	F float64
+}
+
+var (
+	// GRS80（JGD2011が採用）
+	GRS80 = Ellipsoid{A: 6378137, F: 1 / 298.257222101}
+	// WGS84
+	WGS84 = Ellipsoid{A: 6378137, F: 1 / 298.257223563}
+)
+
+// TransverseMercator は横メルカトル図法
+// Forwardの出力は (x, y) = (東距, 北距)（m）
+type TransverseMercator struct {
+	Ellipsoid Ellipsoid
+	// 原点の経度・緯度（度）
+	Lon0, Lat0 float64
+	// 中央子午線上の縮尺係数
+	K0 float64
+	// 東距・北距に加える値（m）
+	FalseEasting, FalseNorthing float64
+
+	// NewTransverseMercatorで事前計算した係数（nilなら毎回計算する）
+	coeffs *tmercSeries
+}
+
+// NewTransverseMercator は係数を事前計算した横メルカトル図法を作る
+func NewTransverseMercator(ellipsoid Ellipsoid, lon0, lat0, k0, falseEasting, falseNorthing float64) TransverseMercator {
+	p := TransverseMercator{
+		Ellipsoid:     ellipsoid,
+		Lon0:          lon0,
+		Lat0:          lat0,
+		K0:            k0,
+		FalseEasting:  falseEasting,
+		FalseNorthing: falseNorthing,
+	}
+	s := p.series()
+	p.coeffs = &s
+	return p
+}
+
+// Krüger級数の係数
+type tmercSeries struct {
+	n     float64
+	scale float64    // K0 * 子午線弧長の係数（A̅）
+	alpha [5]float64 // 順変換
+	beta  [5]float64 // 逆変換
+	delta [6]float64 // 等角緯度から緯度への変換
+	s0    float64    // 原点緯度までの子午線弧長（縮尺係数込み）
+}
+
+func (p TransverseMercator) series() tmercSeries {
+	if p.coeffs != nil {
+		return *p.coeffs
+	}
+	n := p.Ellipsoid.F / (2 - p.Ellipsoid.F)
+	n2, n3, n4, n5 := n*n, n*n*n, n*n*n*n, n*n*n*n*n
+	n6 := n5 * n
+	s := tmercSeries{
+		n:     n,
+		scale: p.K0 * p.Ellipsoid.A / (1 + n) * (1 + n2/4 + n4/64),
+		alpha: [5]float64{
+			n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180 - 127*n5/288,
+			13*n2/48 - 3*n3/5 + 557*n4/1440 + 281*n5/630,
+			61*n3/240 - 103*n4/140 + 15061*n5/26880,
+			49561*n4/161280 - 179*n5/168,
+			34729 * n5 / 80640,
+		},
+		beta: [5]float64{
+			n/2 - 2*n2/3 + 37*n3/96 - n4/360 - 81*n5/512,
+			n2/48 + n3/15 - 437*n4/1440 + 46*n5/105,
+			17*n3/480 - 37*n4/840 - 209*n5/4480,
+			4397*n4/161280 - 11*n5/504,
+			4583 * n5 / 161280,
+		},
+		delta: [6]float64{
+			2*n - 2*n2/3 - 2*n3 + 116*n4/45 + 26*n5/45 - 2854*n6/675,
+			7*n2/3 - 8*n3/5 - 227*n4/45 + 2704*n5/315 + 2323*n6/945,
+			56*n3/15 - 136*n4/35 - 1262*n5/105 + 73814*n6/2835,
+			4279*n4/630 - 332*n5/35 - 399572*n6/14175,
+			4174*n5/315 - 144838*n6/6237,
+			601676 * n6 / 22275,
+		},
+	}
+	// 原点緯度の子午線弧長は、中央子午線上の点の北距として同じ級数で求める
+	s.s0, _ = s.forward(p.Lat0*math.Pi/180, 0)
+	return s
+}
+
+// 中央子午線からの経度差dLon（rad）と緯度lat（rad）から (北距, 東距) を求める（原点補正なし）
+func (s tmercSeries) forward(lat, dLon float64) (northing, easting float64) {
+	k := 2 * math.Sqrt(s.n) / (1 + s.n)
+	t := math.Sinh(math.Atanh(math.Sin(lat)) - k*math.Atanh(k*math.Sin(lat)))
+	tBar := math.Sqrt(1 + t*t)
+	xi := math.Atan2(t, math.Cos(dLon))
+	eta := math.Atanh(math.Sin(dLon) / tBar)
+
+	x, y := xi, eta
+	for j, a := range s.alpha {
+		m := 2 * float64(j+1)
+		x += a * math.Sin(m*xi) * math.Cosh(m*eta)
+		y += a * math.Cos(m*xi) * math.Sinh(m*eta)
+	}
+	return s.scale * x, s.scale * y
+}
+
+func (p TransverseMercator) Forward(lon, lat float64) (float64, float64) {
+	s := p.series()
+	northing, easting := s.forward(lat*math.Pi/180, (lon-p.Lon0)*math.Pi/180)
+	return easting + p.FalseEasting, northing - s.s0 + p.FalseNorthing
+}
+
+func (p TransverseMercator) Inverse(x, y float64) (float64, float64) {
+	s := p.series()
+	xi := (y - p.FalseNorthing + s.s0) / s.scale
+	eta := (x - p.FalseEasting) / s.scale
+
+	xiP, etaP := xi, eta
+	for j, b := range s.beta {
+		m := 2 * float64(j+1)
+		xiP -= b * math.Sin(m*xi) * math.Cosh(m*eta)
+		etaP -= b * math.Cos(m*xi) * math.Sinh(m*eta)
+	}
+	chi := math.Asin(math.Sin(xiP) / math.Cosh(etaP))
+	lat := chi
+	for j, d := range s.delta {
+		lat += d * math.Sin(2*float64(j+1)*chi)
+	}
+	dLon := math.Atan2(math.Sinh(etaP), math.Cos(xiP))
+	return p.Lon0 + dLon*180/math.Pi, lat * 180 / math.Pi
+}
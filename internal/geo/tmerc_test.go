@@ -0,0 +1,76 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// 許容誤差（m）
+const tmercTolerance = 0.001
+
+// 経度緯度の許容誤差（度）。1mm ≒ 1e-8度
+const tmercDegreeTolerance = 1e-8
+
+func TestJGD2011ZoneControlPoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		zone     int
+		lon, lat float64
+		// 東距・北距（m）
+		easting, northing float64
+	}{
+		// 国土地理院「平面直角座標への換算」の計算例（IX系）
+		{"GSI sample", 9, 140.08785504166664, 36.103774791666666, 22916.2436, 11543.6883},
+		// 各系の座標系原点は (0, 0)
+		{"origin I", 1, 129 + 30.0/60, 33, 0, 0},
+		{"origin IX", 9, 139 + 50.0/60, 36, 0, 0},
+		{"origin XIX", 19, 154, 26, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj, err := JGD2011Zone(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			easting, northing := proj.Forward(tt.lon, tt.lat)
+			if math.Abs(easting-tt.easting) > tmercTolerance || math.Abs(northing-tt.northing) > tmercTolerance {
+				t.Errorf("Forward(%v, %v) = (%.4f, %.4f), want (%.4f, %.4f)", tt.lon, tt.lat, easting, northing, tt.easting, tt.northing)
+			}
+			lon, lat := proj.Inverse(tt.easting, tt.northing)
+			if math.Abs(lon-tt.lon) > tmercDegreeTolerance || math.Abs(lat-tt.lat) > tmercDegreeTolerance {
+				t.Errorf("Inverse(%v, %v) = (%.10f, %.10f), want (%.10f, %.10f)", tt.easting, tt.northing, lon, lat, tt.lon, tt.lat)
+			}
+		})
+	}
+}
+
+func TestJGD2011ZoneOutOfRange(t *testing.T) {
+	for _, zone := range []int{0, 20} {
+		if _, err := JGD2011Zone(zone); err == nil {
+			t.Errorf("JGD2011Zone(%d) succeeded, want error", zone)
+		}
+	}
+}
+
+func TestTransverseMercatorRoundTrip(t *testing.T) {
+	for zone := 1; zone <= len(jgd2011Origins); zone++ {
+		proj, err := JGD2011Zone(zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		origin := jgd2011Origins[zone-1]
+		// 原点から東西南北に最大2度ずつずらした点
+		for _, d := range [][2]float64{{0, 0}, {1.5, 0.5}, {-2, 1}, {0.75, -2}, {-1, -1.25}} {
+			lon, lat := origin[1]+d[0], origin[0]+d[1]
+			x, y := proj.Forward(lon, lat)
+			gotLon, gotLat := proj.Inverse(x, y)
+			if math.Abs(gotLon-lon) > tmercDegreeTolerance || math.Abs(gotLat-lat) > tmercDegreeTolerance {
+				t.Errorf("zone %d: (%v, %v) -> (%.4f, %.4f) -> (%.10f, %.10f)", zone, lon, lat, x, y, gotLon, gotLat)
+			}
+			backX, backY := proj.Forward(gotLon, gotLat)
+			if math.Abs(backX-x) > tmercTolerance || math.Abs(backY-y) > tmercTolerance {
+				t.Errorf("zone %d: (%.4f, %.4f) round-trips to (%.4f, %.4f)", zone, x, y, backX, backY)
+			}
+		}
+	}
+}
@@ -0,0 +1,430 @@
+/*
+# topojson.go
+
+TopoJSON（https://github.com/topojson/topojson-specification）を読み込むモジュール
+量子化（transform）と差分符号化されたアークを復元し、アークをつないでリング・ラインを組み立てる
+*/
+package geo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Topologyの1つのオブジェクト（objectsのメンバー）を変換したレイヤー
+type TopologyObject struct {
+	// objectsでのメンバー名
+	Name  string
+	Layer Layer
+}
+
+/*
+ReadTopoJSON
+TopoJSONを読み込み、objectsのオブジェクトごとにLayerを返す（ファイル内の順）。
+GeometryCollectionのオブジェクトはメンバーごとに別のフィーチャーになる。
+診断の位置は "/objects/counties/geometries/3" の形式になる。
+
+ReadLayer にTopologyを渡した場合は、全オブジェクトを1つのレイヤーにまとめて返す。
+*/
+func ReadTopoJSON(r io.Reader) ([]TopologyObject, error) {
+	var root map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("parse JSON: %w", err)
+	}
+	if root == nil {
+		return nil, errors.New("TopoJSON root is not an object")
+	}
+	var rootType string
+	_ = json.Unmarshal(root["type"], &rootType)
+	if rootType != "Topology" {
+		return nil, fmt.Errorf("not a TopoJSON Topology: type %s", rawKind(root["type"]))
+	}
+	names, builders, err := decodeTopology(root)
+	if err != nil {
+		return nil, err
+	}
+	// bboxはトポロジー全体の範囲なので、オブジェクトごとのレイヤーには使わない
+	members := map[string]json.RawMessage{"crs": root["crs"]}
+	objects := make([]TopologyObject, len(names))
+	for i, name := range names {
+		layer, err := builders[i].finish(members)
+		if err != nil {
+			return nil, fmt.Errorf("object %q: %w", name, err)
+		}
+		objects[i] = TopologyObject{Name: name, Layer: layer}
+	}
+	return objects, nil
+}
+
+/*
+ReadTopologyObject
+TopoJSONを読み込み、objectsのうち名前がnameのオブジェクトだけのLayerを返す。
+ReadFile に "counties.topojson#counties" のようなパスを渡したときに使われる。
+*/
+func ReadTopologyObject(r io.Reader, name string) (Layer, error) {
+	objects, err := ReadTopoJSON(r)
+	if err != nil {
+		return Layer{Valid: false}, err
+	}
+	names := make([]string, len(objects))
+	for i, object := range objects {
+		if object.Name == name {
+			return object.Layer, nil
+		}
+		names[i] = fmt.Sprintf("%q", object.Name)
+	}
+	return Layer{Valid: false}, fmt.Errorf("object %q not found (objects: %s)", name, strings.Join(names, ", "))
+}
+
+// Topologyの全オブジェクトを1つのレイヤーにまとめる（ReadLayer から呼ばれる）
+func readTopology(root map[string]json.RawMessage) (Layer, error) {
+	_, builders, err := decodeTopology(root)
+	if err != nil {
+		return Layer{Valid: false}, err
+	}
+	merged := newLayerBuilder()
+	for _, builder := range builders {
+		merged.merge(builder)
+	}
+	return merged.finish(root)
+}
+
+// 別のビルダーのフィーチャー・境界ボックス・診断をまとめて追加する
+func (b *layerBuilder) merge(other *layerBuilder) {
+	b.features = append(b.features, other.features...)
+	b.bound = b.bound.union(other.bound)
+	b.diags = append(b.diags, other.diags...)
+	b.skipped += other.skipped
+	if other.elevation.valid() {
+		b.elevation.extend([]float64{other.elevation.Min, other.elevation.Max})
+	}
+}
+
+// アークを復元したトポロジー
+type topology struct {
+	// 経度緯度（transformを適用済み）のアーク
+	arcs [][][2]float64
+	// 各アークのZ（3つ目の値。量子化も差分符号化もされない）
+	arcMeasures []PathMeasures
+	// transform（無ければnil）
+	scale, translate *[2]float64
+}
+
+// objectsの各オブジェクトを、ファイル内の順にそれぞれのビルダーへ変換する
+func decodeTopology(root map[string]json.RawMessage) ([]string, []*layerBuilder, error) {
+	t := &topology{}
+	if raw, ok := root["transform"]; ok && !isJSONNull(raw) {
+		var transform struct {
+			Scale     *[2]float64 `json:"scale"`
+			Translate *[2]float64 `json:"translate"`
+		}
+		if err := json.Unmarshal(raw, &transform); err != nil || transform.Scale == nil || transform.Translate == nil {
+			return nil, nil, errors.New("transform must have scale and translate pairs")
+		}
+		t.scale, t.translate = transform.Scale, transform.Translate
+	}
+	var arcs [][][]float64
+	if raw, ok := root["arcs"]; ok {
+		if err := json.Unmarshal(raw, &arcs); err != nil {
+			return nil, nil, fmt.Errorf("arcs must be arrays of positions: %w", err)
+		}
+	}
+	for i, arc := range arcs {
+		if err := t.addArc(arc); err != nil {
+			return nil, nil, fmt.Errorf("arc %d: %w", i, err)
+		}
+	}
+
+	names, objects, err := orderedMembers(root["objects"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("objects: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, nil, errors.New("objects not found in TopoJSON")
+	}
+	builders := make([]*layerBuilder, len(names))
+	for i, name := range names {
+		builder := newLayerBuilder()
+		t.addObject(builder, objects[name], pointerKey("/objects", name), true)
+		builders[i] = builder
+	}
+	return names, builders, nil
+}
+
+// アークを1つ復元する。transformがあれば差分を足し合わせてから量子化を戻す
+func (t *topology) addArc(arc [][]float64) error {
+	path := make([][2]float64, len(arc))
+	var measures PathMeasures
+	var x, y float64
+	for i, position := range arc {
+		if len(position) < 2 {
+			return fmt.Errorf("position %d has %d values", i, len(position))
+		}
+		if t.scale != nil {
+			x += position[0]
+			y += position[1]
+			path[i] = [2]float64{x*t.scale[0] + t.translate[0], y*t.scale[1] + t.translate[1]}
+		} else {
+			path[i] = [2]float64{position[0], position[1]}
+		}
+		z := math.NaN()
+		if len(position) > 2 {
+			z = position[2]
+		}
+		measures.Z = appendMeasure(measures.Z, i, z)
+	}
+	t.arcs = append(t.arcs, path)
+	t.arcMeasures = append(t.arcMeasures, measures)
+	return nil
+}
+
+// Point・MultiPointの位置（量子化されているが差分符号化はされていない）
+func (t *topology) position(values []float64) ([2]float64, float64, bool) {
+	if len(values) < 2 {
+		return [2]float64{}, math.NaN(), false
+	}
+	position := [2]float64{values[0], values[1]}
+	if t.scale != nil {
+		position = [2]float64{values[0]*t.scale[0] + t.translate[0], values[1]*t.scale[1] + t.translate[1]}
+	}
+	z := math.NaN()
+	if len(values) > 2 {
+		z = values[2]
+	}
+	return position, z, true
+}
+
+/*
+アークの番号の並びをつないで1本のパスにする。
+負の番号 ~i はアークiを逆向きに使う。2本目以降のアークの始点は直前の終点と同じなので除く。
+*/
+func (t *topology) stitch(indexes []int, pointer string, diags *Diagnostics) ([][2]float64, PathMeasures, bool) {
+	var path [][2]float64
+	var measures PathMeasures
+	for n, index := range indexes {
+		i, reversed := index, false
+		if index < 0 {
+			i, reversed = ^index, true
+		}
+		if i >= len(t.arcs) {
+			diags.errorf(pointerIndex(pointer, n), "arc %d does not exist (%d arcs)", index, len(t.arcs))
+			return nil, PathMeasures{}, false
+		}
+		arc, z := t.arcs[i], t.arcMeasures[i].Z
+		for k := range arc {
+			if reversed {
+				k = len(arc) - 1 - k
+			}
+			if len(path) > 0 && (k == 0 && !reversed || k == len(arc)-1 && reversed) {
+				continue
+			}
+			v := math.NaN()
+			if z != nil {
+				v = z[k]
+			}
+			measures.Z = appendMeasure(measures.Z, len(path), v)
+			path = append(path, arc[k])
+		}
+	}
+	if len(path) == 0 {
+		diags.errorf(pointer, "no arcs")
+		return nil, PathMeasures{}, false
+	}
+	return path, measures, true
+}
+
+// TopoJSONのジオメトリオブジェクト
+type topoGeometry struct {
+	Type        *string                `json:"type"`
+	Arcs        json.RawMessage        `json:"arcs"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+	Geometries  []json.RawMessage      `json:"geometries"`
+	Properties  map[string]interface{} `json:"properties"`
+}
+
+/*
+ジオメトリオブジェクトをフィーチャーとしてビルダーに追加する。
+split がtrueならGeometryCollectionのメンバーを別々のフィーチャーにする（objectsの直下のみ）。
+*/
+func (t *topology) addObject(b *layerBuilder, raw json.RawMessage, pointer string, split bool) {
+	var object topoGeometry
+	if err := json.Unmarshal(raw, &object); err != nil {
+		b.diags.errorf(pointer, "object is %s, not a geometry object; skipped", rawKind(raw))
+		b.skipped++
+		return
+	}
+	if split && object.Type != nil && *object.Type == string(GeometryGeometryCollection) {
+		for i, member := range object.Geometries {
+			t.addObject(b, member, pointerIndex(pointerKey(pointer, "geometries"), i), false)
+		}
+		return
+	}
+	feature := Feature{Valid: true, Properties: object.Properties}
+	if feature.Properties == nil {
+		feature.Properties = map[string]interface{}{}
+	}
+	if object.Type == nil {
+		// "type": null はジオメトリの無いフィーチャー
+		feature.Diagnostics.warnf("/type", "object has no geometry; skipped")
+	} else {
+		geometry := t.geometry(object, &feature.Diagnostics)
+		if geometry.Type != "" {
+			feature.Geometry = &geometry
+		}
+	}
+	b.addFeature(feature, pointer)
+}
+
+// ジオメトリオブジェクトのアーク・座標をジオメトリに変換する。診断はオブジェクトからの相対位置
+func (t *topology) geometry(object topoGeometry, diags *Diagnostics) Geometry {
+	g := Geometry{Type: GeometryType(*object.Type), Bounds: emptyBound()}
+	invalid := func(member string, raw json.RawMessage) Geometry {
+		diags.errorf("/"+member, "%s has invalid %s: %s", g.Type, member, rawKind(raw))
+		return Geometry{Bounds: emptyBound()}
+	}
+
+	switch g.Type {
+	case GeometryPoint:
+		var values []float64
+		if err := json.Unmarshal(object.Coordinates, &values); err != nil {
+			return invalid("coordinates", object.Coordinates)
+		}
+		position, z, ok := t.position(values)
+		if !ok {
+			return invalid("coordinates", object.Coordinates)
+		}
+		g.Parts = []CachedPart{{Exterior: [][2]float64{position}, Measures: pointMeasures(z, math.NaN())}}
+	case GeometryMultiPoint:
+		var list [][]float64
+		if err := json.Unmarshal(object.Coordinates, &list); err != nil {
+			return invalid("coordinates", object.Coordinates)
+		}
+		for i, values := range list {
+			position, z, ok := t.position(values)
+			if !ok {
+				diags.errorf(pointerIndex("/coordinates", i), "position is not a pair of numbers; skipped")
+				continue
+			}
+			g.Parts = append(g.Parts, CachedPart{Exterior: [][2]float64{position}, Measures: pointMeasures(z, math.NaN())})
+		}
+	case GeometryLineString:
+		var indexes []int
+		if err := json.Unmarshal(object.Arcs, &indexes); err != nil {
+			return invalid("arcs", object.Arcs)
+		}
+		if path, measures, ok := t.stitch(indexes, "/arcs", diags); ok {
+			g.Lines = [][][2]float64{path}
+			g.LineMeasures = appendPathMeasures(nil, 0, measures)
+		}
+	case GeometryMultiLineString:
+		var lines [][]int
+		if err := json.Unmarshal(object.Arcs, &lines); err != nil {
+			return invalid("arcs", object.Arcs)
+		}
+		for i, indexes := range lines {
+			if path, measures, ok := t.stitch(indexes, pointerIndex("/arcs", i), diags); ok {
+				g.LineMeasures = appendPathMeasures(g.LineMeasures, len(g.Lines), measures)
+				g.Lines = append(g.Lines, path)
+			}
+		}
+	case GeometryPolygon:
+		var rings [][]int
+		if err := json.Unmarshal(object.Arcs, &rings); err != nil {
+			return invalid("arcs", object.Arcs)
+		}
+		if part, ok := t.polygon(rings, "/arcs", diags); ok {
+			g.Parts = []CachedPart{part}
+		}
+	case GeometryMultiPolygon:
+		var polygons [][][]int
+		if err := json.Unmarshal(object.Arcs, &polygons); err != nil {
+			return invalid("arcs", object.Arcs)
+		}
+		for i, rings := range polygons {
+			if part, ok := t.polygon(rings, pointerIndex("/arcs", i), diags); ok {
+				g.Parts = append(g.Parts, part)
+			}
+		}
+	case GeometryGeometryCollection:
+		// 入れ子のGeometryCollectionは1つのフィーチャーにまとめる
+		for i, member := range object.Geometries {
+			var memberObject topoGeometry
+			memberPointer := pointerIndex("/geometries", i)
+			if err := json.Unmarshal(member, &memberObject); err != nil || memberObject.Type == nil {
+				diags.errorf(memberPointer, "geometry is %s, not a geometry object; skipped", rawKind(member))
+				continue
+			}
+			var memberDiags Diagnostics
+			memberGeometry := t.geometry(memberObject, &memberDiags)
+			diags.addAll(memberPointer, memberDiags)
+			g.Geometries = append(g.Geometries, memberGeometry)
+			g.Bounds = g.Bounds.union(memberGeometry.Bounds)
+		}
+		return g
+	default:
+		diags.errorf("/type", "unsupported geometry type %q", g.Type)
+		return Geometry{Bounds: emptyBound()}
+	}
+	extendBound(&g.Bounds, g.Parts, g.Lines)
+	return g
+}
+
+// リングのアーク番号の並び（[外周, 穴, 穴, ...]）をポリゴンの1パートにする
+func (t *topology) polygon(rings [][]int, pointer string, diags *Diagnostics) (CachedPart, bool) {
+	var part CachedPart
+	for i, indexes := range rings {
+		ring, measures, ok := t.stitch(indexes, pointerIndex(pointer, i), diags)
+		if !ok {
+			if i == 0 {
+				diags.errorf(pointer, "invalid exterior ring; polygon skipped")
+				return CachedPart{}, false
+			}
+			continue
+		}
+		if i == 0 {
+			part.Exterior = ring
+			part.Measures = appendPathMeasures(nil, 0, measures)
+		} else {
+			part.Measures = appendPathMeasures(part.Measures, len(part.Holes)+1, measures)
+			part.Holes = append(part.Holes, ring)
+		}
+	}
+	if part.Exterior == nil {
+		diags.errorf(pointer, "expected an array of linear rings")
+		return CachedPart{}, false
+	}
+	return part, true
+}
+
+// JSONオブジェクトのメンバーをファイル内の順に返す
+func orderedMembers(raw json.RawMessage) ([]string, map[string]json.RawMessage, error) {
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &members); err != nil || isJSONNull(raw) {
+		return nil, nil, fmt.Errorf("expected an object, got %s", rawKind(raw))
+	}
+	var names []string
+	seen := map[string]bool{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	_, _ = dec.Token() // '{'
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, nil, err
+		}
+		// 重複したメンバーは json.Unmarshal と同じく最後の値を1度だけ使う
+		if name, ok := tok.(string); ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, members, nil
+}
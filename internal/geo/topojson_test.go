@@ -0,0 +1,132 @@
+package geo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 量子化・差分符号化されたトポロジー。
+// arc 0: (0,0) (2,0) (2,4) → (100,10) (101,10) (101,11)
+// arc 1: (2,4) (0,4) (0,0) → (101,11) (100,11) (100,10)
+const quantizedTopology = `{
+	"type": "Topology",
+	"transform": {"scale": [0.5, 0.25], "translate": [100, 10]},
+	"arcs": [
+		[[0, 0], [2, 0], [0, 4]],
+		[[2, 4], [-2, 0], [0, -4]]
+	],
+	"objects": {
+		"shapes": {"type": "GeometryCollection", "geometries": [
+			{"type": "Polygon", "arcs": [[0, 1]], "properties": {"name": "square"}},
+			{"type": "LineString", "arcs": [-1], "properties": {"name": "reversed"}}
+		]},
+		"places": {"type": "Point", "coordinates": [4, 8], "properties": {"name": "pin"}}
+	}
+}`
+
+func readTestTopology(t *testing.T) []TopologyObject {
+	t.Helper()
+	objects, err := ReadTopoJSON(strings.NewReader(quantizedTopology))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func TestReadTopoJSONDeltaDecoding(t *testing.T) {
+	objects := readTestTopology(t)
+	if len(objects) != 2 || objects[0].Name != "shapes" || objects[1].Name != "places" {
+		t.Fatalf("objects = %+v, want shapes and places in file order", objects)
+	}
+	shapes := objects[0].Layer.Features
+	if len(shapes) != 2 {
+		t.Fatalf("shapes has %d features, want 2", len(shapes))
+	}
+	// 2本目のアークの始点（1本目の終点と同じ）は除かれる
+	want := [][2]float64{{100, 10}, {101, 10}, {101, 11}, {100, 11}, {100, 10}}
+	if got := shapes[0].Parts[0].Exterior; !reflect.DeepEqual(got, want) {
+		t.Errorf("square = %v, want %v", got, want)
+	}
+}
+
+func TestReadTopoJSONReversedArc(t *testing.T) {
+	line := readTestTopology(t)[0].Layer.Features[1]
+	if line.Name != "reversed" || len(line.Lines) != 1 {
+		t.Fatalf("feature = %+v, want the reversed line", line)
+	}
+	want := [][2]float64{{101, 11}, {101, 10}, {100, 10}}
+	if !reflect.DeepEqual(line.Lines[0], want) {
+		t.Errorf("~0 = %v, want %v", line.Lines[0], want)
+	}
+}
+
+func TestReadTopoJSONQuantizedPoint(t *testing.T) {
+	places := readTestTopology(t)[1].Layer
+	if len(places.Features) != 1 {
+		t.Fatalf("places has %d features, want 1", len(places.Features))
+	}
+	// 点は差分符号化されず、量子化だけを戻す: (4*0.5+100, 8*0.25+10)
+	want := [][2]float64{{102, 12}}
+	if got := places.Features[0].Parts[0].Exterior; !reflect.DeepEqual(got, want) {
+		t.Errorf("pin = %v, want %v", got, want)
+	}
+	if places.Bounds != (Bound{LonMin: 102, LonMax: 102, LatMin: 12, LatMax: 12}) {
+		t.Errorf("Bounds = %+v, want only the point", places.Bounds)
+	}
+}
+
+func TestReadLayerMergesTopologyObjects(t *testing.T) {
+	layer, err := ReadLayer(strings.NewReader(quantizedTopology), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 3 {
+		t.Errorf("%d features, want all 3 objects' features", len(layer.Features))
+	}
+}
+
+func TestReadFileTopologyObject(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.topojson")
+	if err := os.WriteFile(path, []byte(quantizedTopology), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layer, err := ReadFile(path+"#places", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.Features) != 1 || layer.Features[0].Name != "pin" {
+		t.Errorf("features = %+v, want only the places object", layer.Features)
+	}
+
+	_, err = ReadFile(path+"#rivers", nil)
+	if err == nil || !strings.Contains(err.Error(), `"shapes", "places"`) {
+		t.Errorf("err = %v, want the available objects listed", err)
+	}
+}
+
+func TestReadFileMissingPathWithHash(t *testing.T) {
+	dir := t.TempDir()
+	// "#" の前がTopoJSONでなければ、パス全体が見つからないと報告する
+	for _, name := range []string{"a#b.geojson", "missing.topojson#states"} {
+		path := filepath.Join(dir, name)
+		_, err := ReadFile(path, nil)
+		if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), path) {
+			t.Errorf("ReadFile(%q) = %v, want the whole path not found", name, err)
+		}
+	}
+
+	// "#" を含む名前のファイルはそのまま読む
+	path := filepath.Join(dir, "x#places.topojson")
+	if err := os.WriteFile(path, []byte(quantizedTopology), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layer, err := ReadFile(path, nil)
+	if err != nil || len(layer.Features) != 3 {
+		t.Errorf("ReadFile(%q) = %d features, %v, want the whole topology", path, len(layer.Features), err)
+	}
+}
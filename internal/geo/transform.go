@@ -0,0 +1,152 @@
+/*
+# transform.go
+
+表示範囲をTUI座標に写す投影パイプライン
+経度緯度 → 投影法（Projection）で平面座標 → 縦横比を保って拡大縮小・余白を中央寄せ → TUI座標
+*/
+package geo
+
+import "math"
+
+const (
+	// 端末セルの標準的な縦横比（高さ/幅）
+	DefaultCellAspect = 2.0
+
+	// 表示範囲の投影後の範囲を求めるときの1辺あたりの分割数
+	boundSamples = 16
+)
+
+// Transform は表示範囲（経度緯度）とTUI座標を相互に変換する
+type Transform struct {
+	// 表示範囲。縦横比を保つ場合、収まらない方向に余白ができる
+	View       Bound
+	Projection Projection
+	Width      int
+	Height     int
+	// グリッド1マスの縦横比（高さ/幅）。0以下なら縦横比を保たず全体に引き伸ばす
+	Aspect float64
+
+	scaleX, scaleY   float64 // 投影座標1単位あたりのTUI座標
+	offsetX, offsetY float64 // 余白（レターボックス）の大きさ
+	originX, originY float64 // 表示範囲の左上の投影座標
+}
+
+/*
+NewTransform
+表示範囲をwidth x heightのグリッドに収める変換を作る。
+
+【変換ロジック】
+- 投影: projで経度緯度を平面座標にする（nilなら表示範囲の中心緯度を標準緯線とする正距円筒図法）
+- 範囲: 表示範囲の辺を投影して、投影座標での範囲を求める
+- 拡大率: X方向とY方向で物理的な長さが揃うよう、Y方向はaspectで割る
+- 表示範囲全体が収まる拡大率を選び、余った方向は中央に寄せる
+- Y軸は上下反転（上がY=0）
+
+Args:
+
+	view: 表示範囲
+	proj: 投影法
+	width: グリッド幅
+	height: グリッド高さ
+	aspect: グリッド1マスの縦横比（高さ/幅）。0以下なら縦横それぞれ引き伸ばす
+*/
+func NewTransform(view Bound, proj Projection, width, height int, aspect float64) Transform {
+	if proj == nil {
+		_, lat0 := view.Center()
+		proj = Equirectangular{Lat0: lat0}
+	}
+	t := Transform{View: view, Projection: proj, Width: width, Height: height, Aspect: aspect}
+
+	minX, minY, maxX, maxY := projectedBounds(view, proj)
+	spanX := maxX - minX
+	spanY := maxY - minY
+	availX := float64(width - 1)
+	availY := float64(height - 1)
+
+	if aspect > 0 {
+		scale := math.Inf(1)
+		if spanX > 0 {
+			scale = availX / spanX
+		}
+		if spanY > 0 {
+			scale = math.Min(scale, availY*aspect/spanY)
+		}
+		if math.IsInf(scale, 1) {
+			// 範囲が1点だけの場合は拡大率に意味がないので中央に置く
+			scale = 1
+		}
+		t.scaleX, t.scaleY = scale, scale/aspect
+	} else {
+		if spanX > 0 {
+			t.scaleX = availX / spanX
+		}
+		if spanY > 0 {
+			t.scaleY = availY / spanY
+		}
+	}
+
+	t.offsetX = (availX - spanX*t.scaleX) / 2
+	t.offsetY = (availY - spanY*t.scaleY) / 2
+	t.originX = minX
+	t.originY = maxY
+	return t
+}
+
+// 表示範囲の辺上の点を投影し、投影座標での範囲を求める
+func projectedBounds(view Bound, proj Projection) (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for i := 0; i <= boundSamples; i++ {
+		f := float64(i) / boundSamples
+		lon := view.LonMin + f*view.lonSpan()
+		lat := view.LatMin + f*view.latSpan()
+		for _, p := range [][2]float64{
+			{lon, view.LatMin}, {lon, view.LatMax},
+			{view.LonMin, lat}, {view.LonMax, lat},
+		} {
+			x, y := proj.Forward(p[0], p[1])
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+	return minX, minY, maxX, maxY
+}
+
+// Forward は経度緯度をTUI座標（小数）に変換する
+func (t Transform) Forward(lon, lat float64) (x, y float64) {
+	px, py := t.Projection.Forward(lon, lat)
+	x = (px-t.originX)*t.scaleX + t.offsetX
+	y = (t.originY-py)*t.scaleY + t.offsetY
+	return x, y
+}
+
+// Inverse はTUI座標（小数）を経度緯度に変換する
+func (t Transform) Inverse(x, y float64) (lon, lat float64) {
+	if t.Projection == nil {
+		return t.View.Center()
+	}
+	cx, cy := t.Projection.Forward(t.View.Center())
+	px, py := cx, cy
+	if t.scaleX > 0 {
+		px = (x-t.offsetX)/t.scaleX + t.originX
+	}
+	if t.scaleY > 0 {
+		py = t.originY - (y-t.offsetY)/t.scaleY
+	}
+	return t.Projection.Inverse(px, py)
+}
+
+// VisibleBounds はグリッド全体に表示される範囲（余白を含む）を返す
+func (t Transform) VisibleBounds() Bound {
+	lonMin, latMax := t.Inverse(0, 0)
+	lonMax, latMin := t.Inverse(float64(t.Width-1), float64(t.Height-1))
+	return Bound{LonMin: lonMin, LonMax: lonMax, LatMin: latMin, LatMax: latMax}
+}
+
+// CellSize はグリッド中央の1マスの経度緯度での大きさ（経度・緯度方向の大きい方）
+func (t Transform) CellSize() float64 {
+	cx, cy := float64(t.Width-1)/2, float64(t.Height-1)/2
+	lon0, lat0 := t.Inverse(cx, cy)
+	lon1, lat1 := t.Inverse(cx+1, cy+1)
+	return math.Max(math.Abs(lon1-lon0), math.Abs(lat1-lat0))
+}
@@ -9,18 +9,69 @@ type Layer struct {
 	Features []CachedFeature
 	// パースが有効かどうか
 	Valid bool `json:"valid"`
+	// crsメンバー（GeoJSON 2008）で宣言されたCRSの名前。宣言がなければ空
+	CRSName string
+	// FeatureCollectionのbboxメンバーで宣言された境界ボックス。宣言がなければnil
+	DeclaredBounds *Bound
+	// 読み込めずに読み飛ばしたフィーチャーの数（geometryが無い・不正・未対応の型など）
+	Skipped int
+	// 読み込み時の診断（読み飛ばした値やbboxの不一致など）
+	Diagnostics Diagnostics
+	// 全頂点の高さ（Z）の範囲。Zを持つ頂点が無ければnil
+	Elevation *Range
+}
+
+// 初期表示範囲。bboxが宣言されていればそれを、なければ計算した境界ボックスを返す
+func (l Layer) Extent() Bound {
+	if l.DeclaredBounds != nil {
+		return *l.DeclaredBounds
+	}
+	return l.Bounds
 }
 
 type CachedFeature struct {
 	Name       string
 	Properties map[string]interface{}
-	Rings      [][][2]float64 // 経度緯度座標系でのリング
+	Parts      []CachedPart   // 経度緯度座標系でのポリゴン（外周リングと穴）
+	Lines      [][][2]float64 // 経度緯度座標系でのライン（開いたパス）
+	// Lines と同じ順の各ラインのZ・M。どのラインにも無ければnil
+	LineMeasures []PathMeasures
 }
 
 type Polygon struct {
 	Name       string
 	Properties map[string]interface{}
-	Rings      [][][2]int // TUI座標系でのリング
+	Parts      []PolygonPart // TUI座標系でのポリゴン（外周リングと穴）
+	Lines      [][][2]int    // TUI座標系でのライン（開いたパス）
+	// Lines と同じ順の各ラインのZ・M。どのラインにも無ければnil
+	LineMeasures []PathMeasures
+}
+
+// 経度緯度座標系でのポリゴンの1パート
+// Pointは頂点が1つだけの外周リングとして保持する
+type CachedPart struct {
+	Exterior [][2]float64   // 外周リング
+	Holes    [][][2]float64 // 内周リング（穴）
+	// Rings() と同じ順の各リングのZ・M。どのリングにも無ければnil
+	Measures []PathMeasures
+}
+
+// 外周リングと穴をまとめて返す
+func (p CachedPart) Rings() [][][2]float64 {
+	return append([][][2]float64{p.Exterior}, p.Holes...)
+}
+
+// TUI座標系でのポリゴンの1パート
+type PolygonPart struct {
+	Exterior [][2]int   // 外周リング
+	Holes    [][][2]int // 内周リング（穴）
+	// Rings() と同じ順の各リングのZ・M。どのリングにも無ければnil
+	Measures []PathMeasures
+}
+
+// 外周リングと穴をまとめて返す
+func (p PolygonPart) Rings() [][][2]int {
+	return append([][][2]int{p.Exterior}, p.Holes...)
 }
 
 type Bound struct {
@@ -36,9 +87,63 @@ func (b *Bound) lonSpan() float64 {
 	return b.LonMax - b.LonMin
 }
 
+// 境界ボックスの中心
+func (b Bound) Center() (lon, lat float64) {
+	return (b.LonMin + b.LonMax) / 2, (b.LatMin + b.LatMax) / 2
+}
+
+// 中心を固定してfactor倍の範囲にする（factor < 1 で拡大表示）
+func (b Bound) Zoom(factor float64) Bound {
+	lon, lat := b.Center()
+	return b.ZoomAt(lon, lat, factor)
+}
+
+// (lon, lat) の位置を固定してfactor倍の範囲にする
+func (b Bound) ZoomAt(lon, lat, factor float64) Bound {
+	return Bound{
+		LonMin: lon - (lon-b.LonMin)*factor,
+		LonMax: lon + (b.LonMax-lon)*factor,
+		LatMin: lat - (lat-b.LatMin)*factor,
+		LatMax: lat + (b.LatMax-lat)*factor,
+	}
+}
+
+// 範囲の幅・高さに対する割合（dx, dy）だけ移動する（dx > 0 で東、dy > 0 で北）
+func (b Bound) Pan(dx, dy float64) Bound {
+	return b.Translate(b.lonSpan()*dx, b.latSpan()*dy)
+}
+
+// 経度方向にdLon、緯度方向にdLat（度）だけ移動する
+func (b Bound) Translate(dLon, dLat float64) Bound {
+	return Bound{
+		LonMin: b.LonMin + dLon,
+		LonMax: b.LonMax + dLon,
+		LatMin: b.LatMin + dLat,
+		LatMax: b.LatMax + dLat,
+	}
+}
+
 type TuiGeometry struct {
 	Bounds   Bound     `json:"bounds"`
 	Width    int       `json:"width"`
 	Height   int       `json:"height"`
 	Polygons []Polygon `json:"polygons"`
+	// レイヤー全体の高さの範囲（高さに応じた描画の基準）。Zが無ければnil
+	Elevation *Range    `json:"-"`
+	Transform Transform `json:"-"`
+}
+
+// 経度緯度を表示範囲に基づいてTUI座標に変換する（範囲外ならキャンバス外の値になる）
+func (g TuiGeometry) ToTui(lon, lat float64) [2]int {
+	return geometoryToTui(lon, lat, &g.Transform)
+}
+
+// TUI座標（小数可）を表示範囲に基づいて経度緯度に変換する
+func (g TuiGeometry) ToGeo(x, y float64) (lon, lat float64) {
+	return g.Transform.Inverse(x, y)
+}
+
+// TUI座標1つ分の経度緯度での大きさ（経度・緯度方向の大きい方）
+func (g TuiGeometry) CellSize() float64 {
+	return g.Transform.CellSize()
 }
@@ -0,0 +1,165 @@
+/*
+# utm.go
+
+UTM図法と、PROJ形式の横メルカトル図法パラメータ
+*/
+package geo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// WGS84 / UTM 北半球 zone 1 のEPSGコード（zone 60まで連番）
+	utmNorthBaseEPSG = 32601
+	// WGS84 / UTM 南半球 zone 1 のEPSGコード（zone 60まで連番）
+	utmSouthBaseEPSG = 32701
+	utmZones         = 60
+
+	utmScaleFactor   = 0.9996
+	utmFalseEasting  = 500000.0
+	utmSouthNorthing = 10000000.0
+)
+
+// UTMZone はWGS84のUTM図法を返す。southなら南半球（北距に10,000km加算）
+func UTMZone(zone int, south bool) (TransverseMercator, error) {
+	if zone < 1 || zone > utmZones {
+		return TransverseMercator{}, fmt.Errorf("UTM zone out of range (1-60): %d", zone)
+	}
+	falseNorthing := 0.0
+	if south {
+		falseNorthing = utmSouthNorthing
+	}
+	lon0 := float64(zone*6 - 183)
+	return NewTransverseMercator(WGS84, lon0, 0, utmScaleFactor, utmFalseEasting, falseNorthing), nil
+}
+
+func utmCRS(zone int, south bool) (CRS, error) {
+	proj, err := UTMZone(zone, south)
+	if err != nil {
+		return CRS{}, err
+	}
+	code, hemisphere := utmNorthBaseEPSG+zone-1, "N"
+	if south {
+		code, hemisphere = utmSouthBaseEPSG+zone-1, "S"
+	}
+	return CRS{
+		Name:       fmt.Sprintf("EPSG:%d (WGS84 / UTM %d%s)", code, zone, hemisphere),
+		Projection: proj,
+	}, nil
+}
+
+// "54N" / "54S" / "54" を解釈する（半球の省略は北半球）
+func parseUTMZone(value string) (int, bool, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	south := false
+	switch {
+	case strings.HasSuffix(value, "S"):
+		south = true
+		value = strings.TrimSuffix(value, "S")
+	case strings.HasSuffix(value, "N"):
+		value = strings.TrimSuffix(value, "N")
+	}
+	zone, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid UTM zone: %q", value)
+	}
+	return zone, south, nil
+}
+
+/*
+parseProjString
+PROJ形式の文字列から横メルカトル図法のCRSを作る。
+
+対応するパラメータ:
+
+	+proj=tmerc +lat_0= +lon_0= +k=（または +k_0=） +x_0= +y_0= +ellps=GRS80|WGS84
+	+proj=utm +zone= [+south] [+ellps=GRS80|WGS84]
+
+単位はメートルのみ。+datum / +towgs84 / +no_defs / +type は無視する（WGS84と同一視）。
+*/
+func parseProjString(value string) (CRS, error) {
+	params := map[string]string{}
+	for _, token := range strings.Fields(value) {
+		token = strings.TrimPrefix(token, "+")
+		key, val, _ := strings.Cut(token, "=")
+		params[strings.ToLower(key)] = val
+	}
+
+	ellipsoid := WGS84
+	if ellps, ok := params["ellps"]; ok {
+		switch strings.ToUpper(ellps) {
+		case "WGS84":
+		case "GRS80":
+			ellipsoid = GRS80
+		default:
+			return CRS{}, fmt.Errorf("unsupported ellipsoid: %q", ellps)
+		}
+	}
+	if units, ok := params["units"]; ok && units != "m" {
+		return CRS{}, fmt.Errorf("unsupported units: %q", units)
+	}
+
+	number := func(key string, fallback float64) (float64, error) {
+		raw, ok := params[key]
+		if !ok {
+			return fallback, nil
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid +%s: %q", key, raw)
+		}
+		return v, nil
+	}
+
+	switch params["proj"] {
+	case "utm":
+		zone, err := strconv.Atoi(params["zone"])
+		if err != nil {
+			return CRS{}, fmt.Errorf("invalid +zone: %q", params["zone"])
+		}
+		_, south := params["south"]
+		crs, err := utmCRS(zone, south)
+		if err != nil {
+			return CRS{}, err
+		}
+		if ellipsoid != WGS84 {
+			tm := crs.Projection.(TransverseMercator)
+			crs.Projection = NewTransverseMercator(ellipsoid, tm.Lon0, tm.Lat0, tm.K0, tm.FalseEasting, tm.FalseNorthing)
+		}
+		crs.Name = strings.TrimSpace(value)
+		return crs, nil
+	case "tmerc":
+		var values [5]float64
+		for i, p := range []struct {
+			keys     []string
+			fallback float64
+		}{
+			{[]string{"lat_0"}, 0},
+			{[]string{"lon_0"}, 0},
+			{[]string{"k", "k_0"}, 1},
+			{[]string{"x_0"}, 0},
+			{[]string{"y_0"}, 0},
+		} {
+			values[i] = p.fallback
+			for _, key := range p.keys {
+				v, err := number(key, values[i])
+				if err != nil {
+					return CRS{}, err
+				}
+				values[i] = v
+			}
+		}
+		lat0, lon0, k0, falseEasting, falseNorthing := values[0], values[1], values[2], values[3], values[4]
+		if k0 <= 0 {
+			return CRS{}, fmt.Errorf("invalid scale factor: %v", k0)
+		}
+		return CRS{
+			Name:       strings.TrimSpace(value),
+			Projection: NewTransverseMercator(ellipsoid, lon0, lat0, k0, falseEasting, falseNorthing),
+		}, nil
+	}
+	return CRS{}, fmt.Errorf("unsupported projection: %q", params["proj"])
+}
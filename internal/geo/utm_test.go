@@ -0,0 +1,158 @@
+package geo
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestUTMZoneControlPoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		zone     int
+		south    bool
+		lon, lat float64
+		// 東距・北距（m）と許容誤差（m）
+		easting, northing, tolerance float64
+	}{
+		// 中央子午線と赤道の交点は (500000, 0)、南半球は (500000, 10000000)
+		{"equator 54N", 54, false, 141, 0, 500000, 0, tmercTolerance},
+		{"equator 56S", 56, true, 153, 0, 500000, 10000000, tmercTolerance},
+		// 中央子午線上の北極は 0.9996 × 子午線象限長（WGS84: 10001965.7293 m）
+		{"north pole", 33, false, 15, 90, 500000, 0.9996 * 10001965.7293, tmercTolerance},
+		{"south pole", 33, true, 15, -90, 500000, 10000000 - 0.9996*10001965.7293, tmercTolerance},
+		// GeographicLib GeoConvert の例（33.3N 44.4E = 38n 444140.54 3684706.36）。公表値はcm単位
+		{"GeoConvert sample", 38, false, 44.4, 33.3, 444140.54, 3684706.36, 0.005},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj, err := UTMZone(tt.zone, tt.south)
+			if err != nil {
+				t.Fatal(err)
+			}
+			easting, northing := proj.Forward(tt.lon, tt.lat)
+			if math.Abs(easting-tt.easting) > tt.tolerance || math.Abs(northing-tt.northing) > tt.tolerance {
+				t.Errorf("Forward(%v, %v) = (%.4f, %.4f), want (%.4f, %.4f)", tt.lon, tt.lat, easting, northing, tt.easting, tt.northing)
+			}
+		})
+	}
+}
+
+func TestUTMZoneRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		zone     int
+		south    bool
+		lon, lat float64
+	}{
+		{54, false, 139.7671, 35.6812}, // 東京
+		{56, true, 151.2153, -33.8568}, // シドニー
+		{31, false, 2.2945, 48.8584},   // パリ
+		{19, true, -70.6693, -33.4489}, // サンティアゴ
+		{1, false, -179.5, 80},
+		{60, true, 179.5, -80},
+	} {
+		proj, err := UTMZone(tt.zone, tt.south)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := proj.Forward(tt.lon, tt.lat)
+		lon, lat := proj.Inverse(x, y)
+		if math.Abs(lon-tt.lon) > tmercDegreeTolerance || math.Abs(lat-tt.lat) > tmercDegreeTolerance {
+			t.Errorf("UTM %d (south=%v): (%v, %v) -> (%.4f, %.4f) -> (%.10f, %.10f)", tt.zone, tt.south, tt.lon, tt.lat, x, y, lon, lat)
+		}
+	}
+}
+
+func TestUTMZoneOutOfRange(t *testing.T) {
+	for _, zone := range []int{0, 61} {
+		if _, err := UTMZone(zone, false); err == nil {
+			t.Errorf("UTMZone(%d) succeeded, want error", zone)
+		}
+	}
+}
+
+func TestParseCRS(t *testing.T) {
+	utm54N, _ := UTMZone(54, false)
+	utm53S, _ := UTMZone(53, true)
+	zone9, _ := JGD2011Zone(9)
+	tests := []struct {
+		name string
+		// 期待する名前の先頭
+		wantName string
+		// 期待する投影法。nilなら経度緯度
+		want *TransverseMercator
+	}{
+		{"", "WGS84", nil},
+		{"wgs84", "WGS84", nil},
+		{"EPSG:4326", "WGS84", nil},
+		{"CRS84", "WGS84", nil},
+		{"urn:ogc:def:crs:OGC:1.3:CRS84", "WGS84", nil},
+		{"EPSG:6668", "JGD2011", nil},
+		{"EPSG:6677", "EPSG:6677 (JGD2011 / IX)", &zone9},
+		{"epsg:6677", "EPSG:6677 (JGD2011 / IX)", &zone9},
+		{"urn:ogc:def:crs:EPSG::6677", "EPSG:6677 (JGD2011 / IX)", &zone9},
+		{"http://www.opengis.net/def/crs/EPSG/0/6677", "EPSG:6677 (JGD2011 / IX)", &zone9},
+		{"JGD2011:9", "EPSG:6677 (JGD2011 / IX)", &zone9},
+		{"jgd2011:IX", "EPSG:6677 (JGD2011 / IX)", &zone9},
+		{"EPSG:32654", "EPSG:32654 (WGS84 / UTM 54N)", &utm54N},
+		{"EPSG:32753", "EPSG:32753 (WGS84 / UTM 53S)", &utm53S},
+		{"utm:54n", "EPSG:32654 (WGS84 / UTM 54N)", &utm54N},
+		{"UTM:54", "EPSG:32654 (WGS84 / UTM 54N)", &utm54N},
+		{"utm:53s", "EPSG:32753 (WGS84 / UTM 53S)", &utm53S},
+		{"+proj=utm +zone=54 +datum=WGS84 +units=m +no_defs", "+proj=utm +zone=54", &utm54N},
+		{"+proj=utm +zone=53 +south", "+proj=utm +zone=53 +south", &utm53S},
+		{"+proj=tmerc +lat_0=36 +lon_0=139.833333333333 +k=0.9999 +x_0=0 +y_0=0 +ellps=GRS80", "+proj=tmerc", &zone9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crs, err := ParseCRS(tt.name)
+			if err != nil {
+				t.Fatalf("ParseCRS(%q): %v", tt.name, err)
+			}
+			if !strings.HasPrefix(crs.Name, tt.wantName) {
+				t.Errorf("Name = %q, want prefix %q", crs.Name, tt.wantName)
+			}
+			if tt.want == nil {
+				if !crs.IsGeographic() {
+					t.Errorf("Projection = %v, want geographic", crs.Projection)
+				}
+				return
+			}
+			// 投影法は同じ点を同じ座標に写すかで比べる
+			got, ok := crs.Projection.(TransverseMercator)
+			if !ok {
+				t.Fatalf("Projection = %T, want TransverseMercator", crs.Projection)
+			}
+			lon, lat := tt.want.Lon0+0.5, tt.want.Lat0+0.5
+			if tt.want.FalseNorthing != 0 {
+				lat = -30
+			}
+			x, y := got.Forward(lon, lat)
+			wantX, wantY := tt.want.Forward(lon, lat)
+			if math.Abs(x-wantX) > tmercTolerance || math.Abs(y-wantY) > tmercTolerance {
+				t.Errorf("Forward(%v, %v) = (%.4f, %.4f), want (%.4f, %.4f)", lon, lat, x, y, wantX, wantY)
+			}
+		})
+	}
+}
+
+func TestParseCRSErrors(t *testing.T) {
+	for _, name := range []string{
+		"EPSG:3857",
+		"EPSG:abc",
+		"UTM:61N",
+		"utm:x",
+		"JGD2011:20",
+		"JGD2011:XX",
+		"+proj=lcc +lat_1=33",
+		"+proj=tmerc +ellps=bessel",
+		"+proj=tmerc +units=ft",
+		"+proj=tmerc +k=0",
+		"+proj=utm +zone=abc",
+		"nonsense",
+	} {
+		if crs, err := ParseCRS(name); err == nil {
+			t.Errorf("ParseCRS(%q) = %q, want error", name, crs.Name)
+		}
+	}
+}
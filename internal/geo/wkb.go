@@ -0,0 +1,283 @@
+/*
+# wkb.go
+
+ジオメトリのWKB（Well-Known Binary）・EWKB（PostGISのSRID付きWKB）を読み込むモジュール
+*/
+package geo
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// EWKBの型コードのフラグ
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+	// 座標1つの最小の長さ（x, y）
+	wkbPositionSize = 16
+)
+
+// WKBの型コード（ISO/OGC。Z・M付きは +1000・+2000・+3000）
+var wkbGeometryTypes = map[uint32]GeometryType{
+	1: GeometryPoint,
+	2: GeometryLineString,
+	3: GeometryPolygon,
+	4: GeometryMultiPoint,
+	5: GeometryMultiLineString,
+	6: GeometryMultiPolygon,
+	7: GeometryGeometryCollection,
+}
+
+/*
+ParseGeometryHexWKB
+16進数で書かれたWKB・EWKB（PostGISの出力など）を読む。ParseGeometryWKB を参照。
+*/
+func ParseGeometryHexWKB(text string) (Geometry, int, error) {
+	data, err := hex.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return Geometry{}, 0, fmt.Errorf("invalid hex WKB: %w", err)
+	}
+	return ParseGeometryWKB(data)
+}
+
+/*
+ParseGeometryWKB
+WKB・EWKBのジオメトリを読み、ジオメトリとSRID（EWKBでSRIDが無ければ0）を返す。
+
+ISO/OGCのZ・M（型コード +1000・+2000・+3000）とEWKBのフラグのどちらにも対応する。
+バイト順はジオメトリ（メンバーを含む）ごとの指定に従う。
+POINT EMPTY（座標がNaN）は頂点の無いジオメトリになる。
+*/
+func ParseGeometryWKB(data []byte) (Geometry, int, error) {
+	r := &wkbReader{data: data}
+	g, srid, err := r.geometry()
+	if err != nil {
+		return Geometry{}, 0, err
+	}
+	if r.pos != len(r.data) {
+		return Geometry{}, 0, fmt.Errorf("%d bytes left after the geometry", len(r.data)-r.pos)
+	}
+	return g, srid, nil
+}
+
+// WKBを先頭から読む
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	// 読んでいるジオメトリの次元（Z・M）
+	hasZ, hasM bool
+}
+
+var errWKBTruncated = errors.New("WKB is truncated")
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errWKBTruncated
+	}
+	v := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *wkbReader) float64() (float64, error) {
+	if r.pos+8 > len(r.data) {
+		return 0, errWKBTruncated
+	}
+	v := math.Float64frombits(r.order.Uint64(r.data[r.pos:]))
+	r.pos += 8
+	return v, nil
+}
+
+// 要素の数を読む。残りのバイト数で収まらない数は壊れているものとして扱う
+func (r *wkbReader) count(minSize int) (int, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if int64(n)*int64(minSize) > int64(len(r.data)-r.pos) {
+		return 0, fmt.Errorf("count %d exceeds the remaining WKB", n)
+	}
+	return int(n), nil
+}
+
+// バイト順・型コード（とEWKBのSRID）から始まるジオメトリを1つ読む
+func (r *wkbReader) geometry() (Geometry, int, error) {
+	if r.pos >= len(r.data) {
+		return Geometry{}, 0, errWKBTruncated
+	}
+	switch r.data[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return Geometry{}, 0, fmt.Errorf("invalid byte order %d at offset %d", r.data[r.pos], r.pos)
+	}
+	r.pos++
+	code, err := r.uint32()
+	if err != nil {
+		return Geometry{}, 0, err
+	}
+	srid := 0
+	if code&ewkbSRID != 0 {
+		v, err := r.uint32()
+		if err != nil {
+			return Geometry{}, 0, err
+		}
+		srid = int(int32(v))
+	}
+	base := code &^ (ewkbZ | ewkbM | ewkbSRID)
+	kind, ok := wkbGeometryTypes[base%1000]
+	if !ok || base/1000 > 3 {
+		return Geometry{}, 0, fmt.Errorf("unsupported WKB geometry type %d", base)
+	}
+	r.hasZ = code&ewkbZ != 0 || base/1000 == 1 || base/1000 == 3
+	r.hasM = code&ewkbM != 0 || base/1000 == 2 || base/1000 == 3
+
+	g, err := r.body(kind)
+	return g, srid, err
+}
+
+// 型に応じたジオメトリの中身を読む
+func (r *wkbReader) body(kind GeometryType) (Geometry, error) {
+	switch kind {
+	case GeometryPoint:
+		position, z, m, err := r.position()
+		if err != nil {
+			return Geometry{}, err
+		}
+		if math.IsNaN(position[0]) && math.IsNaN(position[1]) {
+			return Geometry{Type: kind, Bounds: emptyBound()}, nil
+		}
+		return simpleGeometry(kind, []CachedPart{{Exterior: [][2]float64{position}, Measures: pointMeasures(z, m)}}, nil, nil), nil
+	case GeometryLineString:
+		path, measures, err := r.path()
+		if err != nil {
+			return Geometry{}, err
+		}
+		if len(path) == 0 {
+			return Geometry{Type: kind, Bounds: emptyBound()}, nil
+		}
+		return simpleGeometry(kind, nil, [][][2]float64{path}, appendPathMeasures(nil, 0, measures)), nil
+	case GeometryPolygon:
+		part, err := r.polygon()
+		if err != nil || len(part.Exterior) == 0 {
+			return Geometry{Type: kind, Bounds: emptyBound()}, err
+		}
+		return simpleGeometry(kind, []CachedPart{part}, nil, nil), nil
+	}
+
+	// マルチ型とGeometryCollectionはメンバーがそれぞれWKBのジオメトリになっている
+	n, err := r.count(wkbPositionSize / 2)
+	if err != nil {
+		return Geometry{}, err
+	}
+	members := make([]Geometry, 0, n)
+	for i := 0; i < n; i++ {
+		member, _, err := r.geometry()
+		if err != nil {
+			return Geometry{}, fmt.Errorf("member %d: %w", i, err)
+		}
+		members = append(members, member)
+	}
+	if kind == GeometryGeometryCollection {
+		return collectionGeometry(members), nil
+	}
+	var parts []CachedPart
+	var lines [][][2]float64
+	var lineMeasures []PathMeasures
+	for i, member := range members {
+		if member.Type != wkbMemberType(kind) {
+			return Geometry{}, fmt.Errorf("member %d of %s is %s", i, kind, member.Type)
+		}
+		parts = append(parts, member.Parts...)
+		if len(member.Lines) > 0 {
+			var measures PathMeasures
+			if len(member.LineMeasures) > 0 {
+				measures = member.LineMeasures[0]
+			}
+			lineMeasures = appendPathMeasures(lineMeasures, len(lines), measures)
+			lines = append(lines, member.Lines[0])
+		}
+	}
+	return simpleGeometry(kind, parts, lines, lineMeasures), nil
+}
+
+// マルチ型のメンバーの型
+func wkbMemberType(kind GeometryType) GeometryType {
+	switch kind {
+	case GeometryMultiPoint:
+		return GeometryPoint
+	case GeometryMultiLineString:
+		return GeometryLineString
+	}
+	return GeometryPolygon
+}
+
+// 座標を1つ読む。zとmは無ければNaN
+func (r *wkbReader) position() (position [2]float64, z, m float64, err error) {
+	z, m = math.NaN(), math.NaN()
+	if position[0], err = r.float64(); err != nil {
+		return
+	}
+	if position[1], err = r.float64(); err != nil {
+		return
+	}
+	if r.hasZ {
+		if z, err = r.float64(); err != nil {
+			return
+		}
+	}
+	if r.hasM {
+		m, err = r.float64()
+	}
+	return
+}
+
+func (r *wkbReader) path() ([][2]float64, PathMeasures, error) {
+	n, err := r.count(wkbPositionSize)
+	if err != nil {
+		return nil, PathMeasures{}, err
+	}
+	path := make([][2]float64, 0, n)
+	var measures PathMeasures
+	for i := 0; i < n; i++ {
+		position, z, m, err := r.position()
+		if err != nil {
+			return nil, PathMeasures{}, err
+		}
+		measures.Z = appendMeasure(measures.Z, len(path), z)
+		measures.M = appendMeasure(measures.M, len(path), m)
+		path = append(path, position)
+	}
+	return path, measures, nil
+}
+
+// リングの並び（外周、穴...）を読む。リングが無ければ Exterior が空になる
+func (r *wkbReader) polygon() (CachedPart, error) {
+	n, err := r.count(4)
+	if err != nil {
+		return CachedPart{}, err
+	}
+	var part CachedPart
+	for i := 0; i < n; i++ {
+		ring, measures, err := r.path()
+		if err != nil {
+			return CachedPart{}, err
+		}
+		if i == 0 {
+			part.Exterior = ring
+		} else {
+			part.Holes = append(part.Holes, ring)
+		}
+		part.Measures = appendPathMeasures(part.Measures, i, measures)
+	}
+	return part, nil
+}
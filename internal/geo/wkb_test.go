@@ -0,0 +1,120 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+// バイト順・型コードと値（uint32は要素の数・SRID、float64は座標）からWKBを組み立てる
+func wkbBytes(order binary.ByteOrder, code uint32, values ...interface{}) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	_ = binary.Write(&buf, order, code)
+	for _, v := range values {
+		switch v := v.(type) {
+		case []byte:
+			buf.Write(v)
+		default:
+			_ = binary.Write(&buf, order, v)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestParseGeometryWKBDimensions(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	nan := math.NaN()
+	tests := []struct {
+		name       string
+		data       []byte
+		srid       int
+		x, y, z, m float64
+	}{
+		{"2D", wkbBytes(le, 1, 1.0, 2.0), 0, 1, 2, nan, nan},
+		{"big endian", wkbBytes(be, 1, 1.0, 2.0), 0, 1, 2, nan, nan},
+		{"ISO Z", wkbBytes(le, 1001, 1.0, 2.0, 3.0), 0, 1, 2, 3, nan},
+		{"ISO M", wkbBytes(le, 2001, 1.0, 2.0, 4.0), 0, 1, 2, nan, 4},
+		{"ISO ZM", wkbBytes(le, 3001, 1.0, 2.0, 3.0, 4.0), 0, 1, 2, 3, 4},
+		{"EWKB Z", wkbBytes(le, ewkbZ|1, 1.0, 2.0, 3.0), 0, 1, 2, 3, nan},
+		{"EWKB M", wkbBytes(be, ewkbM|1, 1.0, 2.0, 4.0), 0, 1, 2, nan, 4},
+		{"EWKB ZM SRID", wkbBytes(le, ewkbZ|ewkbM|ewkbSRID|1, uint32(4326), 1.0, 2.0, 3.0, 4.0), 4326, 1, 2, 3, 4},
+		{"ISO LineString Z", wkbBytes(le, 1002, uint32(2), 1.0, 2.0, 3.0, 5.0, 6.0, 7.0), 0, 1, 2, 3, nan},
+		{"EWKB MultiPoint SRID", wkbBytes(le, ewkbSRID|4, uint32(3857), uint32(1), wkbBytes(be, 1, 1.0, 2.0)), 3857, 1, 2, nan, nan},
+	}
+	for _, tt := range tests {
+		g, srid, err := ParseGeometryWKB(tt.data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		x, y, z, m := firstVertex(g)
+		if srid != tt.srid || x != tt.x || y != tt.y || !sameValue(z, tt.z) || !sameValue(m, tt.m) {
+			t.Errorf("%s: SRID %d (%v %v z=%v m=%v), want SRID %d (%v %v z=%v m=%v)",
+				tt.name, srid, x, y, z, m, tt.srid, tt.x, tt.y, tt.z, tt.m)
+		}
+	}
+}
+
+func TestParseGeometryWKBEmpty(t *testing.T) {
+	le := binary.LittleEndian
+	nan := math.NaN()
+	tests := []struct {
+		name string
+		data []byte
+		kind GeometryType
+	}{
+		{"POINT EMPTY", wkbBytes(le, 1, nan, nan), GeometryPoint},
+		{"LINESTRING EMPTY", wkbBytes(le, 2, uint32(0)), GeometryLineString},
+		{"POLYGON EMPTY", wkbBytes(le, 3, uint32(0)), GeometryPolygon},
+		{"GEOMETRYCOLLECTION EMPTY", wkbBytes(le, 7, uint32(0)), GeometryGeometryCollection},
+	}
+	for _, tt := range tests {
+		g, _, err := ParseGeometryWKB(tt.data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if g.Type != tt.kind || len(g.Parts) != 0 || len(g.Lines) != 0 {
+			t.Errorf("%s: %s with %d parts and %d lines, want an empty %s", tt.name, g.Type, len(g.Parts), len(g.Lines), tt.kind)
+		}
+	}
+}
+
+func TestParseGeometryHexWKB(t *testing.T) {
+	// PostGISの ST_AsEWKB('SRID=4326;POINT(139.7 35.6)') の16進表記
+	text := hex.EncodeToString(wkbBytes(binary.LittleEndian, ewkbSRID|1, uint32(4326), 139.7, 35.6))
+	g, srid, err := ParseGeometryHexWKB(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, y, _, _ := firstVertex(g); srid != 4326 || x != 139.7 || y != 35.6 {
+		t.Errorf("SRID %d (%v %v), want 4326 (139.7 35.6)", srid, x, y)
+	}
+}
+
+func TestParseGeometryWKBErrors(t *testing.T) {
+	le := binary.LittleEndian
+	point := wkbBytes(le, 1, 1.0, 2.0)
+	tests := map[string][]byte{
+		"empty":            nil,
+		"byte order":       append([]byte{2}, point[1:]...),
+		"truncated":        point[:len(point)-1],
+		"trailing bytes":   append(point, 0),
+		"unsupported type": wkbBytes(le, 17, 1.0, 2.0),
+		"dimension code":   wkbBytes(le, 4001, 1.0, 2.0),
+		"huge count":       wkbBytes(le, 2, uint32(1<<30)),
+		"wrong member":     wkbBytes(le, 5, uint32(1), point),
+	}
+	for name, data := range tests {
+		if _, _, err := ParseGeometryWKB(data); err == nil {
+			t.Errorf("%s: succeeded, want error", name)
+		}
+	}
+}
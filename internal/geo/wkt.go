@@ -0,0 +1,451 @@
+/*
+# wkt.go
+
+ジオメトリのWKT（Well-Known Text）・EWKT（PostGISのSRID付きWKT）を読み込むモジュール
+1行に1ジオメトリのテキストファイルをLayer型に変換する ReadWKTLines もここに置く
+（.prj のCRSのWKTは prj.go を参照）
+*/
+package geo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WKTのジオメトリ型の名前（長いものから照合する）
+var wktGeometryTypes = []struct {
+	name string
+	kind GeometryType
+}{
+	{"GEOMETRYCOLLECTION", GeometryGeometryCollection},
+	{"MULTILINESTRING", GeometryMultiLineString},
+	{"MULTIPOLYGON", GeometryMultiPolygon},
+	{"MULTIPOINT", GeometryMultiPoint},
+	{"LINESTRING", GeometryLineString},
+	{"POLYGON", GeometryPolygon},
+	{"POINT", GeometryPoint},
+}
+
+/*
+ParseGeometryWKT
+WKT・EWKTのジオメトリを読み、ジオメトリとSRID（"SRID=4326;" が無ければ0）を返す。
+
+	POINT (139.7 35.6)
+	LINESTRING Z (139 35 10, 140 36 20)
+	SRID=6677;POLYGON ((0 0, 10 0, 10 10, 0 0), (2 2, 3 2, 3 3, 2 2))
+	MULTIPOINT ((1 2), (3 4)) / MULTIPOINT (1 2, 3 4)
+	GEOMETRYCOLLECTION (POINT (1 2), LINESTRING (0 0, 1 1))
+
+次元の指定（Z・M・ZM。"POINTZ" のような続け書きも可）が無ければ、
+座標が3つならZ、4つならZとMとして読む。EMPTY は頂点の無いジオメトリになる。
+*/
+func ParseGeometryWKT(text string) (Geometry, int, error) {
+	text = strings.TrimSpace(text)
+	srid := 0
+	if head, rest, ok := strings.Cut(text, ";"); ok && strings.HasPrefix(strings.ToUpper(head), "SRID=") {
+		v, err := strconv.Atoi(strings.TrimSpace(head[len("SRID="):]))
+		if err != nil {
+			return Geometry{}, 0, fmt.Errorf("invalid SRID %q", head)
+		}
+		srid = v
+		text = rest
+	}
+	l := &wktLexer{text: text}
+	g, err := l.geometry()
+	if err != nil {
+		return Geometry{}, 0, err
+	}
+	if l.skipSpace(); l.pos != len(l.text) {
+		return Geometry{}, 0, fmt.Errorf("unexpected %q at offset %d", l.text[l.pos:l.pos+1], l.pos)
+	}
+	return g, srid, nil
+}
+
+// ジオメトリのWKTを読む字句解析器
+type wktLexer struct {
+	text string
+	pos  int
+	// 読んでいるジオメトリの次元の指定（Z・M）
+	hasZ, hasM bool
+}
+
+func (l *wktLexer) skipSpace() {
+	for l.pos < len(l.text) && strings.IndexByte(" \t\r\n", l.text[l.pos]) >= 0 {
+		l.pos++
+	}
+}
+
+// 次の文字（空白は読み飛ばす）。終端なら0
+func (l *wktLexer) peek() byte {
+	l.skipSpace()
+	if l.pos >= len(l.text) {
+		return 0
+	}
+	return l.text[l.pos]
+}
+
+func (l *wktLexer) expect(c byte) error {
+	if l.peek() != c {
+		return l.unexpected(fmt.Sprintf("'%c'", c))
+	}
+	l.pos++
+	return nil
+}
+
+func (l *wktLexer) unexpected(want string) error {
+	if l.pos >= len(l.text) {
+		return fmt.Errorf("expected %s at end of WKT", want)
+	}
+	return fmt.Errorf("expected %s at offset %d, got %q", want, l.pos, l.text[l.pos:l.pos+1])
+}
+
+// 英字の並び（大文字にそろえる）
+func (l *wktLexer) word() string {
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.text) && isWKTLetter(l.text[l.pos]) {
+		l.pos++
+	}
+	return strings.ToUpper(l.text[start:l.pos])
+}
+
+func isWKTLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// 次が EMPTY ならそれを読んでtrue
+func (l *wktLexer) empty() bool {
+	start := l.pos
+	if l.word() == "EMPTY" {
+		return true
+	}
+	l.pos = start
+	return false
+}
+
+// 型の名前と次元の指定を読み、ジオメトリを組み立てる
+func (l *wktLexer) geometry() (Geometry, error) {
+	start := l.pos
+	name := l.word()
+	kind, dims := GeometryType(""), ""
+	for _, t := range wktGeometryTypes {
+		if rest, ok := strings.CutPrefix(name, t.name); ok {
+			kind, dims = t.kind, rest
+			break
+		}
+	}
+	if kind == "" {
+		if name == "" {
+			l.pos = start
+			return Geometry{}, l.unexpected("a geometry type")
+		}
+		return Geometry{}, fmt.Errorf("unsupported geometry type %q", name)
+	}
+	if dims == "" {
+		before := l.pos
+		if dims = l.word(); dims == "EMPTY" {
+			dims, l.pos = "", before
+		}
+	}
+	switch dims {
+	case "":
+		l.hasZ, l.hasM = false, false
+	case "Z", "M", "ZM":
+		l.hasZ, l.hasM = strings.Contains(dims, "Z"), strings.Contains(dims, "M")
+	default:
+		return Geometry{}, fmt.Errorf("invalid dimension %q after %s", dims, kind)
+	}
+
+	if l.empty() {
+		return Geometry{Type: kind, Bounds: emptyBound()}, nil
+	}
+	switch kind {
+	case GeometryPoint:
+		part, err := l.point()
+		return simpleGeometry(kind, []CachedPart{part}, nil, nil), err
+	case GeometryMultiPoint:
+		var parts []CachedPart
+		err := l.list(func() error {
+			if l.empty() {
+				return nil
+			}
+			// 各点を括弧で囲む形と囲まない形のどちらも受け付ける
+			if l.peek() != '(' {
+				position, z, m, err := l.position()
+				parts = append(parts, CachedPart{Exterior: [][2]float64{position}, Measures: pointMeasures(z, m)})
+				return err
+			}
+			part, err := l.point()
+			parts = append(parts, part)
+			return err
+		})
+		return simpleGeometry(kind, parts, nil, nil), err
+	case GeometryLineString:
+		path, measures, err := l.path()
+		return simpleGeometry(kind, nil, [][][2]float64{path}, appendPathMeasures(nil, 0, measures)), err
+	case GeometryMultiLineString:
+		var lines [][][2]float64
+		var lineMeasures []PathMeasures
+		err := l.list(func() error {
+			if l.empty() {
+				return nil
+			}
+			path, measures, err := l.path()
+			lineMeasures = appendPathMeasures(lineMeasures, len(lines), measures)
+			lines = append(lines, path)
+			return err
+		})
+		return simpleGeometry(kind, nil, lines, lineMeasures), err
+	case GeometryPolygon:
+		part, err := l.polygon()
+		return simpleGeometry(kind, []CachedPart{part}, nil, nil), err
+	case GeometryMultiPolygon:
+		var parts []CachedPart
+		err := l.list(func() error {
+			if l.empty() {
+				return nil
+			}
+			part, err := l.polygon()
+			parts = append(parts, part)
+			return err
+		})
+		return simpleGeometry(kind, parts, nil, nil), err
+	}
+
+	var members []Geometry
+	err := l.list(func() error {
+		member, err := l.geometry()
+		members = append(members, member)
+		return err
+	})
+	return collectionGeometry(members), err
+}
+
+// 括弧で囲まれたカンマ区切りの並びを読む
+func (l *wktLexer) list(item func() error) error {
+	if err := l.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		if l.peek() != ',' {
+			return l.expect(')')
+		}
+		l.pos++
+	}
+}
+
+// 座標 "x y [z [m]]" を読む。zとmは無ければNaN
+func (l *wktLexer) position() (position [2]float64, z, m float64, err error) {
+	var values []float64
+	for {
+		c := l.peek()
+		if c == ',' || c == ')' || c == 0 {
+			break
+		}
+		start := l.pos
+		for l.pos < len(l.text) && strings.IndexByte("+-.0123456789eE", l.text[l.pos]) >= 0 {
+			l.pos++
+		}
+		v, parseErr := strconv.ParseFloat(l.text[start:l.pos], 64)
+		if parseErr != nil {
+			l.pos = start
+			return position, 0, 0, l.unexpected("a number")
+		}
+		values = append(values, v)
+	}
+	if len(values) < 2 || len(values) > 4 {
+		return position, 0, 0, fmt.Errorf("coordinate before offset %d has %d values, expected 2 to 4", l.pos, len(values))
+	}
+	return wktPosition(values, l.hasZ, l.hasM)
+}
+
+// 座標の値を次元の指定に従って経度緯度・Z・Mに割り当てる
+func wktPosition(values []float64, hasZ, hasM bool) (position [2]float64, z, m float64, err error) {
+	z, m = math.NaN(), math.NaN()
+	position = [2]float64{values[0], values[1]}
+	extra := values[2:]
+	switch {
+	case !hasZ && !hasM:
+		// 指定が無ければ3つ目はZ、4つ目はM
+	case len(extra) != btoi(hasZ)+btoi(hasM):
+		return position, z, m, fmt.Errorf("coordinate has %d values, but the dimension needs %d", len(values), 2+btoi(hasZ)+btoi(hasM))
+	case hasM && !hasZ:
+		m = extra[0]
+		return position, z, m, nil
+	}
+	if len(extra) > 0 {
+		z = extra[0]
+	}
+	if len(extra) > 1 {
+		m = extra[1]
+	}
+	return position, z, m, nil
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// "(x y)" を頂点が1つだけの外周リングとして読む
+func (l *wktLexer) point() (CachedPart, error) {
+	var part CachedPart
+	err := l.list(func() error {
+		if part.Exterior != nil {
+			return errors.New("point has more than one coordinate")
+		}
+		position, z, m, err := l.position()
+		part = CachedPart{Exterior: [][2]float64{position}, Measures: pointMeasures(z, m)}
+		return err
+	})
+	return part, err
+}
+
+// "(x y, x y, ...)" を読む
+func (l *wktLexer) path() ([][2]float64, PathMeasures, error) {
+	var path [][2]float64
+	var measures PathMeasures
+	err := l.list(func() error {
+		position, z, m, err := l.position()
+		measures.Z = appendMeasure(measures.Z, len(path), z)
+		measures.M = appendMeasure(measures.M, len(path), m)
+		path = append(path, position)
+		return err
+	})
+	return path, measures, err
+}
+
+// "((外周), (穴), ...)" を読む
+func (l *wktLexer) polygon() (CachedPart, error) {
+	var part CachedPart
+	rings := 0
+	err := l.list(func() error {
+		ring, measures, err := l.path()
+		if rings == 0 {
+			part.Exterior = ring
+		} else {
+			part.Holes = append(part.Holes, ring)
+		}
+		part.Measures = appendPathMeasures(part.Measures, rings, measures)
+		rings++
+		return err
+	})
+	return part, err
+}
+
+// ポリゴン（点を含む）とラインから境界ボックス付きのジオメトリを作る
+func simpleGeometry(kind GeometryType, parts []CachedPart, lines [][][2]float64, lineMeasures []PathMeasures) Geometry {
+	g := Geometry{Type: kind, Parts: parts, Lines: lines, LineMeasures: lineMeasures, Bounds: emptyBound()}
+	extendBound(&g.Bounds, parts, lines)
+	return g
+}
+
+// メンバーから GeometryCollection を作る
+func collectionGeometry(members []Geometry) Geometry {
+	g := Geometry{Type: GeometryGeometryCollection, Geometries: members, Bounds: emptyBound()}
+	for _, member := range members {
+		g.Bounds = g.Bounds.union(member.Bounds)
+	}
+	return g
+}
+
+/*
+ReadWKTLines
+1行に1つのジオメトリ（WKT・EWKT・16進数のWKB/EWKB）を書いたテキストを読み、
+行ごとのフィーチャーを持つLayerを返す。空行と "#" で始まる行は読み飛ばす。
+
+フィーチャーのpropertiesには行番号（line、1から）と、SRIDがあれば srid が入る。
+最初に現れたSRIDを "EPSG:<SRID>" としてCRSに使い（ParseCRS が対応していなければ
+警告してWGS84として読む）、それと違うSRIDの行は読み飛ばす。診断の位置は "/lines/<行番号-1>" になる。
+*/
+func ReadWKTLines(r io.Reader) (Layer, error) {
+	reader := bufio.NewReader(r)
+	builder := newLayerBuilder()
+	srid, sridLine := 0, 0
+	geometries := 0
+	for lineNumber := 1; ; lineNumber++ {
+		text, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return Layer{Valid: false}, fmt.Errorf("read line %d: %w", lineNumber, err)
+		}
+		if lineNumber == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		text = strings.TrimSpace(text)
+		if text != "" && !strings.HasPrefix(text, "#") {
+			pointer := pointerIndex("/lines", lineNumber-1)
+			feature := Feature{Valid: true, Properties: map[string]interface{}{"line": lineNumber}}
+			geometry, lineSRID, parseErr := parseGeometryLine(text)
+			if lineSRID != 0 {
+				feature.Properties["srid"] = lineSRID
+				if srid == 0 {
+					srid, sridLine = lineSRID, lineNumber
+				}
+			}
+			switch {
+			case parseErr != nil:
+				feature.Diagnostics.errorf("", "%v; skipped", parseErr)
+			case lineSRID != 0 && lineSRID != srid:
+				// 1つのLayerのCRSは1つなので、最初のSRIDと違う行は読まない
+				feature.Diagnostics.errorf("", "SRID %d differs from SRID %d on line %d; skipped", lineSRID, srid, sridLine)
+			case geometry.Bounds.LonMin > geometry.Bounds.LonMax:
+				feature.Diagnostics.warnf("", "empty geometry; skipped")
+			default:
+				feature.Geometry = &geometry
+			}
+			builder.addFeature(feature, pointer)
+			geometries++
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if geometries == 0 {
+		return Layer{Valid: false}, errors.New("no geometries in WKT file")
+	}
+
+	layer, err := builder.finish(nil)
+	if err != nil {
+		return layer, err
+	}
+	if srid != 0 && srid != 4326 {
+		name := "EPSG:" + strconv.Itoa(srid)
+		if _, err := ParseCRS(name); err != nil {
+			layer.Diagnostics.warnf(pointerIndex("/lines", sridLine-1), "unsupported SRID %d; reading as WGS84", srid)
+		} else {
+			layer.CRSName = name
+		}
+	}
+	return layer, nil
+}
+
+// 1行のジオメトリを読む。16進数だけの行はWKB、それ以外はWKTとして読む
+func parseGeometryLine(text string) (Geometry, int, error) {
+	if isHexText(text) {
+		return ParseGeometryHexWKB(text)
+	}
+	return ParseGeometryWKT(text)
+}
+
+func isHexText(text string) bool {
+	if len(text) == 0 || len(text)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') && !(c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package geo
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// 1つ目の頂点の座標とZ・M（無ければNaN）
+func firstVertex(g Geometry) (x, y, z, m float64) {
+	z, m = math.NaN(), math.NaN()
+	var path [][2]float64
+	var measures []PathMeasures
+	switch {
+	case len(g.Parts) > 0:
+		path, measures = g.Parts[0].Exterior, g.Parts[0].Measures
+	case len(g.Lines) > 0:
+		path, measures = g.Lines[0], g.LineMeasures
+	default:
+		return math.NaN(), math.NaN(), z, m
+	}
+	if len(measures) > 0 {
+		if measures[0].Z != nil {
+			z = measures[0].Z[0]
+		}
+		if measures[0].M != nil {
+			m = measures[0].M[0]
+		}
+	}
+	return path[0][0], path[0][1], z, m
+}
+
+// NaN同士を等しいとみなして比べる
+func sameValue(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+func TestParseGeometryWKTDimensions(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		text       string
+		kind       GeometryType
+		x, y, z, m float64
+	}{
+		{"POINT (1 2)", GeometryPoint, 1, 2, nan, nan},
+		{"POINT Z (1 2 3)", GeometryPoint, 1, 2, 3, nan},
+		{"POINT M (1 2 4)", GeometryPoint, 1, 2, nan, 4},
+		{"POINT ZM (1 2 3 4)", GeometryPoint, 1, 2, 3, 4},
+		{"POINTZM(1 2 3 4)", GeometryPoint, 1, 2, 3, 4},
+		// 次元の指定が無ければ3つ目はZ、4つ目はM
+		{"POINT (1 2 3)", GeometryPoint, 1, 2, 3, nan},
+		{"point (1 2 3 4)", GeometryPoint, 1, 2, 3, 4},
+		{"LINESTRING M (1 2 4, 5 6 8)", GeometryLineString, 1, 2, nan, 4},
+		{"MULTIPOINT Z (1 2 3, 4 5 6)", GeometryMultiPoint, 1, 2, 3, nan},
+		{"POLYGON Z ((0 0 1, 1 0 1, 1 1 1, 0 0 1))", GeometryPolygon, 0, 0, 1, nan},
+	}
+	for _, tt := range tests {
+		g, _, err := ParseGeometryWKT(tt.text)
+		if err != nil {
+			t.Errorf("%s: %v", tt.text, err)
+			continue
+		}
+		x, y, z, m := firstVertex(g)
+		if g.Type != tt.kind || x != tt.x || y != tt.y || !sameValue(z, tt.z) || !sameValue(m, tt.m) {
+			t.Errorf("%s: %s (%v %v z=%v m=%v), want %s (%v %v z=%v m=%v)",
+				tt.text, g.Type, x, y, z, m, tt.kind, tt.x, tt.y, tt.z, tt.m)
+		}
+	}
+}
+
+func TestParseGeometryWKTEmpty(t *testing.T) {
+	tests := []struct {
+		text   string
+		kind   GeometryType
+		points [][2]float64
+	}{
+		{"POINT EMPTY", GeometryPoint, nil},
+		{"POINT Z EMPTY", GeometryPoint, nil},
+		{"LINESTRING EMPTY", GeometryLineString, nil},
+		{"MULTIPOLYGON EMPTY", GeometryMultiPolygon, nil},
+		{"GEOMETRYCOLLECTION EMPTY", GeometryGeometryCollection, nil},
+		{"MULTIPOINT ((1 2), EMPTY)", GeometryMultiPoint, [][2]float64{{1, 2}}},
+		{"MULTIPOINT (EMPTY, (1 2))", GeometryMultiPoint, [][2]float64{{1, 2}}},
+		{"MULTIPOINT (EMPTY, 1 2, (3 4))", GeometryMultiPoint, [][2]float64{{1, 2}, {3, 4}}},
+	}
+	for _, tt := range tests {
+		g, _, err := ParseGeometryWKT(tt.text)
+		if err != nil {
+			t.Errorf("%s: %v", tt.text, err)
+			continue
+		}
+		var points [][2]float64
+		for _, part := range g.Parts {
+			points = append(points, part.Exterior...)
+		}
+		if g.Type != tt.kind || !reflect.DeepEqual(points, tt.points) || len(g.Lines) != 0 {
+			t.Errorf("%s: %s %v, want %s %v", tt.text, g.Type, points, tt.kind, tt.points)
+		}
+	}
+
+	g, _, err := ParseGeometryWKT("MULTILINESTRING (EMPTY, (0 0, 1 1))")
+	if err != nil || len(g.Lines) != 1 {
+		t.Errorf("MULTILINESTRING with an EMPTY member: %v, %v", g.Lines, err)
+	}
+}
+
+func TestParseGeometryWKTSRID(t *testing.T) {
+	tests := []struct {
+		text string
+		srid int
+	}{
+		{"POINT (1 2)", 0},
+		{"SRID=4326;POINT (1 2)", 4326},
+		{"srid=6677; POINT (1 2)", 6677},
+		{"  SRID=3857;POINT(1 2)  ", 3857},
+	}
+	for _, tt := range tests {
+		g, srid, err := ParseGeometryWKT(tt.text)
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if x, y, _, _ := firstVertex(g); srid != tt.srid || x != 1 || y != 2 {
+			t.Errorf("%q: SRID %d (%v %v), want %d (1 2)", tt.text, srid, x, y, tt.srid)
+		}
+	}
+}
+
+func TestParseGeometryWKTErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"CIRCLE (1 2)",
+		"POINT (1)",
+		"POINT (1 2",
+		"POINT (1 2, 3 4)",
+		"POINT Z (1 2)",
+		"POINT ZM (1 2 3)",
+		"POINT Q (1 2)",
+		"POINT (1 2) x",
+		"SRID=x;POINT (1 2)",
+		"LINESTRING (0 0, a 1)",
+	} {
+		if _, _, err := ParseGeometryWKT(text); err == nil {
+			t.Errorf("%q: succeeded, want error", text)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+/*
+# braille.go
+
+Unicode点字（U+2800〜U+28FF）で1セルあたり2x4ドットを表現するキャンバス
+*/
+package raster
+
+import "strings"
+
+const (
+	// 点字1文字あたりの横方向ドット数
+	BrailleDotsX = 2
+	// 点字1文字あたりの縦方向ドット数
+	BrailleDotsY = 4
+
+	brailleBase = 0x2800
+)
+
+// ドット位置（[y][x]）に対応するビット
+var brailleBits = [BrailleDotsY][BrailleDotsX]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// BrailleCanvas はドット単位の描画バッファ
+// Width/Heightはドット数で、出力はセル数 ceil(Width/2) x ceil(Height/4) になる
+type BrailleCanvas struct {
+	Width  int
+	Height int
+	dots   [][]bool
+}
+
+// NewBrailleCanvas は全ドットが消灯したキャンバスを作成する
+func NewBrailleCanvas(width, height int) *BrailleCanvas {
+	dots := make([][]bool, height)
+	for y := range dots {
+		dots[y] = make([]bool, width)
+	}
+	return &BrailleCanvas{Width: width, Height: height, dots: dots}
+}
+
+// Set は(x, y)のドットを点灯する。空白以外の文字はすべて点灯として扱う。
+func (c *BrailleCanvas) Set(x, y int, r rune) {
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return
+	}
+	c.dots[y][x] = r != ' '
+}
+
+// Size はキャンバスのドット数を返す
+func (c *BrailleCanvas) Size() (int, int) {
+	return c.Width, c.Height
+}
+
+// String はドットを点字文字に詰めて改行区切りの文字列にする
+// ドットが1つもないセルは空白にする
+func (c *BrailleCanvas) String() string {
+	cols := (c.Width + BrailleDotsX - 1) / BrailleDotsX
+	rows := (c.Height + BrailleDotsY - 1) / BrailleDotsY
+	lines := make([]string, rows)
+	for row := 0; row < rows; row++ {
+		cells := make([]rune, cols)
+		for col := 0; col < cols; col++ {
+			var bits rune
+			for dy := 0; dy < BrailleDotsY; dy++ {
+				for dx := 0; dx < BrailleDotsX; dx++ {
+					x, y := col*BrailleDotsX+dx, row*BrailleDotsY+dy
+					if x < c.Width && y < c.Height && c.dots[y][x] {
+						bits |= brailleBits[dy][dx]
+					}
+				}
+			}
+			if bits == 0 {
+				cells[col] = ' '
+			} else {
+				cells[col] = brailleBase + bits
+			}
+		}
+		lines[row] = string(cells)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,60 @@
+/*
+# canvas.go
+
+TUI座標系のジオメトリを文字セルに描画するためのバッファ
+*/
+package raster
+
+import "strings"
+
+// Surface は描画先。Canvas（1セル1文字）とBrailleCanvas（1セル2x4ドット）がある
+type Surface interface {
+	// (x, y)に文字を書き込む。範囲外は無視する
+	Set(x, y int, r rune)
+	// 描画可能な範囲（Setで使う座標系での幅と高さ）
+	Size() (width, height int)
+	// 改行区切りの文字列にする
+	String() string
+}
+
+// Canvas はセル単位の描画バッファ
+type Canvas struct {
+	Width  int
+	Height int
+	cells  [][]rune
+}
+
+// NewCanvas は空白で初期化したキャンバスを作成する
+func NewCanvas(width, height int) *Canvas {
+	cells := make([][]rune, height)
+	for y := 0; y < height; y++ {
+		row := make([]rune, width)
+		for x := 0; x < width; x++ {
+			row[x] = ' '
+		}
+		cells[y] = row
+	}
+	return &Canvas{Width: width, Height: height, cells: cells}
+}
+
+// Set は(x, y)のセルに文字を書き込む。範囲外は無視する。
+func (c *Canvas) Set(x, y int, r rune) {
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return
+	}
+	c.cells[y][x] = r
+}
+
+// Size はキャンバスのセル数を返す
+func (c *Canvas) Size() (int, int) {
+	return c.Width, c.Height
+}
+
+// String はキャンバスを改行区切りの文字列にする
+func (c *Canvas) String() string {
+	lines := make([]string, len(c.cells))
+	for i, row := range c.cells {
+		lines[i] = string(row)
+	}
+	return strings.Join(lines, "\n")
+}
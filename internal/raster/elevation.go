@@ -0,0 +1,81 @@
+/*
+# elevation.go
+
+高さ（Z）に応じた文字でラインと点を描画する
+*/
+package raster
+
+import (
+	"math"
+
+	"asciigis/internal/geo"
+)
+
+// ElevationGlyphsが空のときに使う文字（低い方から高い方へ）
+var DefaultElevationGlyphs = []rune(".:-=+*#%@")
+
+// elevationGlyph は高さzを範囲rの中の位置に応じた文字に変換する
+// zがNaNならfalseを返す
+func (s Style) elevationGlyph(z float64, r geo.Range) (rune, bool) {
+	if math.IsNaN(z) {
+		return 0, false
+	}
+	glyphs := s.ElevationGlyphs
+	if len(glyphs) == 0 {
+		glyphs = DefaultElevationGlyphs
+	}
+	// 範囲に幅が無ければ中央の文字を使う
+	t := 0.5
+	if r.Max > r.Min {
+		t = (z - r.Min) / (r.Max - r.Min)
+	}
+	i := int(math.Round(t * float64(len(glyphs)-1)))
+	if i < 0 {
+		i = 0
+	} else if i >= len(glyphs) {
+		i = len(glyphs) - 1
+	}
+	return glyphs[i], true
+}
+
+/*
+elevationPath は各線分を両端の高さの平均に応じた文字で描画する。
+片方の端にしか高さが無ければその値を使い、どちらにも無い線分は style のまま描画する。
+
+Args:
+
+	surface: 描画先
+	path: TUI座標のパス
+	z: パスの各頂点の高さ（添字は path と同じ）
+	r: 文字を割り当てる高さの範囲（レイヤー全体の最小・最大）
+	style: 高さの無い線分に使う描画方法
+*/
+func (s Style) elevationPath(surface Surface, path [][2]int, z []float64, r geo.Range, style LineStyle) {
+	at := func(i int) float64 {
+		if i < len(z) {
+			return z[i]
+		}
+		return math.NaN()
+	}
+	segment := func(i, j int) {
+		mean := (at(i) + at(j)) / 2
+		switch {
+		case math.IsNaN(at(i)):
+			mean = at(j)
+		case math.IsNaN(at(j)):
+			mean = at(i)
+		}
+		segmentStyle := style
+		if glyph, ok := s.elevationGlyph(mean, r); ok {
+			segmentStyle = LineStyle{Glyph: glyph}
+		}
+		Line(surface, path[i], path[j], segmentStyle)
+	}
+	if len(path) == 1 {
+		segment(0, 0)
+		return
+	}
+	for i := 1; i < len(path); i++ {
+		segment(i-1, i)
+	}
+}
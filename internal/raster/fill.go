@@ -0,0 +1,127 @@
+/*
+# fill.go
+
+TUI座標系のリングをスキャンラインで塗りつぶす
+*/
+package raster
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FillRule はポリゴン内部の判定規則
+type FillRule int
+
+const (
+	// 交差数が奇数なら内部（穴は向きに関係なく抜ける）
+	EvenOdd FillRule = iota
+	// 外周の巻き数が0でなく、どの穴の巻き数も0なら内部
+	NonZero
+)
+
+// ParseFillRule は "evenodd" / "nonzero" を FillRule に変換する
+func ParseFillRule(name string) (FillRule, error) {
+	switch name {
+	case "evenodd", "even-odd":
+		return EvenOdd, nil
+	case "nonzero", "non-zero":
+		return NonZero, nil
+	}
+	return EvenOdd, fmt.Errorf("unknown fill rule: %q", name)
+}
+
+func (r FillRule) String() string {
+	if r == NonZero {
+		return "nonzero"
+	}
+	return "evenodd"
+}
+
+// スキャンラインとリングの辺との交差
+type crossing struct {
+	x    float64
+	ring int // 0が外周、1以降が穴
+	dir  int // 辺が下向きなら+1、上向きなら-1
+}
+
+// Fill はリング群（[0]が外周、以降が穴）の内部をglyphで塗りつぶす
+// 行ごとにセル中心（整数座標）でスキャンし、頂点は半開区間で数える
+func Fill(s Surface, rings [][][2]int, width, height int, rule FillRule, glyph rune) {
+	for y := 0; y < height; y++ {
+		crossings := scanline(rings, y)
+		if len(crossings) == 0 {
+			continue
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+		winding := make([]int, len(rings))
+		next := 0
+		for x := 0; x < width; x++ {
+			for next < len(crossings) && crossings[next].x <= float64(x) {
+				winding[crossings[next].ring] += crossings[next].dir
+				next++
+			}
+			if next == len(crossings) {
+				// 全ての交差を過ぎたら残りは外部
+				break
+			}
+			if inside(winding, rule) {
+				s.Set(x, y, glyph)
+			}
+		}
+	}
+}
+
+// 行yと各リングの辺との交差を列挙する
+func scanline(rings [][][2]int, y int) []crossing {
+	var crossings []crossing
+	fy := float64(y)
+	for ringIndex, ring := range rings {
+		n := len(ring)
+		if n < 3 {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			a, b := ring[i], ring[(i+1)%n]
+			ay, by := float64(a[1]), float64(b[1])
+			if ay == by {
+				continue
+			}
+			dir := 1
+			if ay > by {
+				a, b = b, a
+				ay, by = by, ay
+				dir = -1
+			}
+			// 上端を含み下端を含まない
+			if fy < ay || fy >= by {
+				continue
+			}
+			t := (fy - ay) / (by - ay)
+			x := float64(a[0]) + t*float64(b[0]-a[0])
+			crossings = append(crossings, crossing{x: x, ring: ringIndex, dir: dir})
+		}
+	}
+	return crossings
+}
+
+// 各リングの巻き数から内部かどうかを判定する
+func inside(winding []int, rule FillRule) bool {
+	if rule == EvenOdd {
+		total := 0
+		for _, w := range winding {
+			total += absInt(w)
+		}
+		return total%2 == 1
+	}
+	if winding[0] == 0 {
+		return false
+	}
+	for _, w := range winding[1:] {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
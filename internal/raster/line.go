@@ -0,0 +1,134 @@
+/*
+# line.go
+
+Bresenhamのアルゴリズムによる線分のラスタライズ
+*/
+package raster
+
+import "math"
+
+// LineStyle は線分の描画方法
+type LineStyle struct {
+	// 線分に使う文字
+	Glyph rune
+	// trueの場合、線分の向きに応じて '-', '|', '/', '\' を使う
+	Directional bool
+}
+
+// Line はaからbまでの線分を描画する
+func Line(s Surface, a, b [2]int, style LineStyle) {
+	glyph := style.Glyph
+	if style.Directional && a != b {
+		glyph = directionGlyph(b[0]-a[0], b[1]-a[1])
+	}
+
+	// 描画範囲外の部分を切り落とす（拡大表示時の長い線分対策）
+	width, height := s.Size()
+	a, b, ok := clipSegment(a, b, width, height)
+	if !ok {
+		return
+	}
+
+	x0, y0 := a[0], a[1]
+	x1, y1 := b[0], b[1]
+	dx := absInt(x1 - x0)
+	dy := -absInt(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		s.Set(x0, y0, glyph)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// Path は連続する頂点を線分でつなぐ。closedの場合は終点と始点もつなぐ。
+func Path(s Surface, path [][2]int, closed bool, style LineStyle) {
+	if len(path) == 1 {
+		Line(s, path[0], path[0], style)
+		return
+	}
+	for i := 1; i < len(path); i++ {
+		Line(s, path[i-1], path[i], style)
+	}
+	if closed && len(path) > 2 && path[0] != path[len(path)-1] {
+		Line(s, path[len(path)-1], path[0], style)
+	}
+}
+
+// clipSegment はLiang-Barskyのアルゴリズムで線分を [0, width-1] x [0, height-1] に切り取る
+// 線分が範囲と交わらない場合はfalseを返す
+func clipSegment(a, b [2]int, width, height int) ([2]int, [2]int, bool) {
+	x0, y0 := float64(a[0]), float64(a[1])
+	dx, dy := float64(b[0]-a[0]), float64(b[1]-a[1])
+	t0, t1 := 0.0, 1.0
+	edges := [4][2]float64{
+		{-dx, x0},
+		{dx, float64(width-1) - x0},
+		{-dy, y0},
+		{dy, float64(height-1) - y0},
+	}
+	for _, edge := range edges {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return a, b, false
+			}
+			t0 = math.Max(t0, t)
+		} else {
+			if t < t0 {
+				return a, b, false
+			}
+			t1 = math.Min(t1, t)
+		}
+	}
+	clipped := func(t float64) [2]int {
+		return [2]int{int(math.Round(x0 + t*dx)), int(math.Round(y0 + t*dy))}
+	}
+	return clipped(t0), clipped(t1), true
+}
+
+// 線分の傾きから描画文字を選ぶ（Y軸は下向き）
+func directionGlyph(dx, dy int) rune {
+	adx, ady := absInt(dx), absInt(dy)
+	switch {
+	case 2*ady <= adx:
+		return '-'
+	case 2*adx <= ady:
+		return '|'
+	case (dx > 0) == (dy > 0):
+		return '\\'
+	default:
+		return '/'
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package raster
+
+import "testing"
+
+func TestClipSegment(t *testing.T) {
+	// 範囲は [0, 9] x [0, 4]
+	const width, height = 10, 5
+	tests := []struct {
+		name   string
+		a, b   [2]int
+		ok     bool
+		ca, cb [2]int
+	}{
+		{"inside", [2]int{1, 1}, [2]int{3, 2}, true, [2]int{1, 1}, [2]int{3, 2}},
+		{"left of the range", [2]int{-5, 1}, [2]int{-1, 3}, false, [2]int{}, [2]int{}},
+		{"above and parallel", [2]int{0, -1}, [2]int{9, -1}, false, [2]int{}, [2]int{}},
+		{"just past the right edge", [2]int{10, 0}, [2]int{10, 4}, false, [2]int{}, [2]int{}},
+		// 外接矩形は範囲と重なるが、線分は角の外を通る
+		{"past the corner", [2]int{-2, 3}, [2]int{3, 8}, false, [2]int{}, [2]int{}},
+		{"crossing horizontally", [2]int{-5, 2}, [2]int{15, 2}, true, [2]int{0, 2}, [2]int{9, 2}},
+		{"crossing diagonally", [2]int{-2, -2}, [2]int{6, 6}, true, [2]int{0, 0}, [2]int{4, 4}},
+		{"reversed", [2]int{15, 2}, [2]int{-5, 2}, true, [2]int{9, 2}, [2]int{0, 2}},
+		{"along the edge", [2]int{0, 0}, [2]int{9, 0}, true, [2]int{0, 0}, [2]int{9, 0}},
+		{"touching the corner", [2]int{-3, 4}, [2]int{0, 4}, true, [2]int{0, 4}, [2]int{0, 4}},
+	}
+	for _, tt := range tests {
+		ca, cb, ok := clipSegment(tt.a, tt.b, width, height)
+		if ok != tt.ok || ok && (ca != tt.ca || cb != tt.cb) {
+			t.Errorf("%s: clipSegment(%v, %v) = %v, %v, %v, want %v, %v, %v", tt.name, tt.a, tt.b, ca, cb, ok, tt.ca, tt.cb, tt.ok)
+		}
+	}
+}
+
+func TestBrailleBits(t *testing.T) {
+	// ドット (x, y) と点字のビット（U+2800からのオフセット）
+	tests := []struct {
+		x, y int
+		want rune
+	}{
+		{0, 0, 0x01}, {0, 1, 0x02}, {0, 2, 0x04}, {0, 3, 0x40},
+		{1, 0, 0x08}, {1, 1, 0x10}, {1, 2, 0x20}, {1, 3, 0x80},
+	}
+	all := NewBrailleCanvas(BrailleDotsX, BrailleDotsY)
+	for _, tt := range tests {
+		c := NewBrailleCanvas(BrailleDotsX, BrailleDotsY)
+		c.Set(tt.x, tt.y, '#')
+		if got := c.String(); got != string(rune(brailleBase)+tt.want) {
+			t.Errorf("dot (%d, %d) = %q (U+%04X), want U+%04X", tt.x, tt.y, got, []rune(got)[0], brailleBase+tt.want)
+		}
+		all.Set(tt.x, tt.y, '#')
+	}
+	if got := all.String(); got != "⣿" {
+		t.Errorf("all dots = %q, want ⣿", got)
+	}
+}
+
+func TestBrailleCanvasCells(t *testing.T) {
+	// 3x5ドットは2x2セルになり、端のセルは欠けたドットを持たない
+	c := NewBrailleCanvas(3, 5)
+	c.Set(2, 4, '#')
+	c.Set(3, 4, '#') // 範囲外
+	c.Set(0, 0, ' ') // 空白は点灯しない
+	if got, want := c.String(), "  \n ⠁"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestFillLeavesHoles(t *testing.T) {
+	exterior := [][2]int{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	sameWay := [][2]int{{3, 3}, {6, 3}, {6, 6}, {3, 6}}
+	otherWay := [][2]int{{3, 3}, {3, 6}, {6, 6}, {6, 3}}
+	for _, rule := range []FillRule{EvenOdd, NonZero} {
+		for name, hole := range map[string][][2]int{"same orientation": sameWay, "opposite orientation": otherWay} {
+			c := NewCanvas(12, 12)
+			Fill(c, [][][2]int{exterior, hole}, 12, 12, rule, '#')
+			for y := 0; y < 12; y++ {
+				for x := 0; x < 12; x++ {
+					// 上端・左端を含み、下端・右端を含まない
+					want := x < 10 && y < 10 && !(x >= 3 && x < 6 && y >= 3 && y < 6)
+					if got := c.cells[y][x] == '#'; got != want {
+						t.Errorf("%s, hole in %s: cell (%d, %d) filled = %v, want %v\n%s", rule, name, x, y, got, want, c.String())
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+# render.go
+
+TuiGeometryをキャンバスに描画する
+*/
+package raster
+
+import (
+	"unicode/utf8"
+
+	"asciigis/internal/geo"
+)
+
+const (
+	// ポリゴンの輪郭に使う文字
+	outlineGlyph = '*'
+	// ラインに使う文字
+	lineGlyph = '#'
+
+	// フィーチャーごとに塗りつぶし文字を指定するプロパティ名（先頭の1文字を使う）
+	FillGlyphProperty = "fill-glyph"
+)
+
+// FillGlyphsが空のときに使う塗りつぶし文字
+var DefaultFillGlyphs = []rune{'░', '▒', '.'}
+
+// Style は描画オプション
+type Style struct {
+	// 線分の向きに応じた文字で描画するか
+	DirectionGlyphs bool
+	// 点字で1セルあたり2x4ドットの解像度で描画するか
+	Braille bool
+	// ポリゴンの内部を塗りつぶすか
+	Fill bool
+	// 塗りつぶしに使う文字。フィーチャーの順に繰り返し割り当てる
+	FillGlyphs []rune
+	// 塗りつぶしの内部判定規則
+	FillRule FillRule
+	// ラインと点を高さ（Z）に応じた文字で描画するか（Brailleでは無効）
+	Elevation bool
+	// 高さに応じて使う文字（低い方から高い方へ）
+	ElevationGlyphs []rune
+}
+
+// fillGlyph はi番目のフィーチャーの塗りつぶし文字を返す
+func (s Style) fillGlyph(i int, polygon geo.Polygon) rune {
+	if value, ok := polygon.Properties[FillGlyphProperty].(string); ok {
+		if r, _ := utf8.DecodeRuneInString(value); r != utf8.RuneError {
+			return r
+		}
+	}
+	glyphs := s.FillGlyphs
+	if len(glyphs) == 0 {
+		glyphs = DefaultFillGlyphs
+	}
+	return glyphs[i%len(glyphs)]
+}
+
+// GridSize はセル数width x heightのキャンバスに対する投影先グリッドの大きさを返す
+// Brailleの場合はドット数（2*width x 4*height）になる
+func (s Style) GridSize(width, height int) (int, int) {
+	if s.Braille {
+		return width * BrailleDotsX, height * BrailleDotsY
+	}
+	return width, height
+}
+
+// GridAspect はセルの縦横比（高さ/幅）に対する投影先グリッド1マスの縦横比を返す
+// Brailleの場合はドット1つ分（セルの1/2 x 1/4）になる
+func (s Style) GridAspect(cellAspect float64) float64 {
+	if s.Braille {
+		return cellAspect * BrailleDotsX / BrailleDotsY
+	}
+	return cellAspect
+}
+
+// Render はジオメトリの全フィーチャーをキャンバスに描画して文字列で返す
+// Brailleの場合、geometryはGridSizeで求めたドット数で投影されている必要がある
+func Render(geometry geo.TuiGeometry, style Style) string {
+	var canvas Surface
+	if style.Braille {
+		canvas = NewBrailleCanvas(geometry.Width, geometry.Height)
+	} else {
+		canvas = NewCanvas(geometry.Width, geometry.Height)
+	}
+	directional := style.DirectionGlyphs && !style.Braille
+	outline := LineStyle{Glyph: outlineGlyph, Directional: directional}
+	line := LineStyle{Glyph: lineGlyph, Directional: directional}
+	// 高さで描き分けるのはレイヤーにZがあり、文字で描画する場合だけ
+	elevation := style.Elevation && !style.Braille && geometry.Elevation != nil
+
+	// 塗りつぶしを先に描画し、輪郭を上に重ねる
+	if style.Fill {
+		var target Surface = canvas
+		if style.Braille {
+			target = checkerSurface{canvas}
+		}
+		for i, polygon := range geometry.Polygons {
+			glyph := style.fillGlyph(i, polygon)
+			for _, part := range polygon.Parts {
+				Fill(target, part.Rings(), geometry.Width, geometry.Height, style.FillRule, glyph)
+			}
+		}
+	}
+
+	for _, polygon := range geometry.Polygons {
+		for _, part := range polygon.Parts {
+			// 穴も外周と同様に輪郭を描画する
+			for i, ring := range part.Rings() {
+				// 点（頂点が1つのリング）は高さで描き分ける
+				if elevation && len(ring) == 1 && i < len(part.Measures) {
+					style.elevationPath(canvas, ring, part.Measures[i].Z, *geometry.Elevation, outline)
+					continue
+				}
+				Path(canvas, ring, true, outline)
+			}
+		}
+		for i, path := range polygon.Lines {
+			if elevation && i < len(polygon.LineMeasures) {
+				style.elevationPath(canvas, path, polygon.LineMeasures[i].Z, *geometry.Elevation, line)
+				continue
+			}
+			Path(canvas, path, false, line)
+		}
+	}
+	return canvas.String()
+}
+
+// checkerSurface は市松模様のドットだけを書き込む
+// 点字の塗りつぶしで輪郭と区別できるようにするために使う
+type checkerSurface struct {
+	Surface
+}
+
+func (c checkerSurface) Set(x, y int, r rune) {
+	if (x+y)%2 == 0 {
+		c.Surface.Set(x, y, r)
+	}
+}
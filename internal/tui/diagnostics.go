@@ -0,0 +1,96 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"asciigis/internal/geo"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// diagnosticsLines is the number of diagnostics shown at once in the panel.
+const diagnosticsLines = 8
+
+var (
+	diagnosticsStyle = lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("#FB5607")).
+				Padding(0, 1)
+
+	diagErrorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF006E"))
+
+	diagWarningStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FFBE0B"))
+)
+
+// toggleDiagnostics shows the diagnostics panel from the top, or hides it.
+func (m model) toggleDiagnostics() model {
+	if m.diagnosticsOn {
+		m.diagnosticsOn = false
+		return m
+	}
+	if len(m.geoData.Diagnostics) == 0 {
+		return m
+	}
+	m.diagnosticsOn = true
+	m.diagnosticsOffset = 0
+	return m
+}
+
+// handleDiagnosticsKey scrolls the diagnostics panel while it is shown.
+// ok is false for keys the panel does not handle.
+func (m model) handleDiagnosticsKey(key string) (model, bool) {
+	switch key {
+	case "esc", "e":
+		m.diagnosticsOn = false
+		return m, true
+	case "up", "k":
+		return m.scrollDiagnostics(-1), true
+	case "down", "j":
+		return m.scrollDiagnostics(1), true
+	case "pgup":
+		return m.scrollDiagnostics(-diagnosticsLines), true
+	case "pgdown":
+		return m.scrollDiagnostics(diagnosticsLines), true
+	case "home":
+		return m.scrollDiagnostics(-len(m.geoData.Diagnostics)), true
+	case "end":
+		return m.scrollDiagnostics(len(m.geoData.Diagnostics)), true
+	}
+	return m, false
+}
+
+// scrollDiagnostics moves the first visible diagnostic by delta, keeping the panel full.
+func (m model) scrollDiagnostics(delta int) model {
+	last := maxInt(len(m.geoData.Diagnostics)-diagnosticsLines, 0)
+	m.diagnosticsOffset = clampInt(m.diagnosticsOffset+delta, 0, last)
+	return m
+}
+
+// diagnosticsPanel lists the visible window of layer diagnostics, one per line.
+func (m model) diagnosticsPanel(width int) string {
+	diags := m.geoData.Diagnostics
+	end := minInt(m.diagnosticsOffset+diagnosticsLines, len(diags))
+	lines := []string{fmt.Sprintf("Diagnostics: %s (%d-%d of %d)", diags.Summary(), m.diagnosticsOffset+1, end, len(diags))}
+	for _, diagnostic := range diags[m.diagnosticsOffset:end] {
+		line := truncateRunes(diagnostic.String(), width)
+		if diagnostic.Severity == geo.SeverityError {
+			line = diagErrorStyle.Render(line)
+		} else {
+			line = diagWarningStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return diagnosticsStyle.Width(width + diagnosticsStyle.GetHorizontalPadding()).Render(strings.Join(lines, "\n"))
+}
+
+// truncateRunes shortens s to width runes, marking the cut with "...".
+func truncateRunes(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width || width < 4 {
+		return s
+	}
+	return string(runes[:width-3]) + "..."
+}
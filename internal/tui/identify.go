@@ -0,0 +1,192 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"asciigis/internal/geo"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// minPanelWidth is the narrowest identify panel placed beside the map;
+	// with less room the panel goes below the map instead.
+	minPanelWidth = 28
+	maxPanelWidth = 48
+)
+
+var (
+	cursorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF006E")).
+			Bold(true)
+
+	panelStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#FFBE0B")).
+			Padding(0, 1)
+
+	panelKeyStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFBE0B"))
+)
+
+// toggleCursor shows the identify crosshair at the view center, or hides it.
+func (m model) toggleCursor() model {
+	if m.cursorOn {
+		m.cursorOn = false
+		return m
+	}
+	if !m.geoData.Valid || m.geometry.Width == 0 {
+		return m
+	}
+	m.cursorOn = true
+	m.cursorLon, m.cursorLat = m.cellToGeo(m.geometry, m.mapWidth/2, m.mapHeight/2)
+	return m.pickAtCursor()
+}
+
+// handleCursorKey moves the crosshair one cell per key press while it is shown.
+// ok is false for keys the crosshair does not handle.
+func (m model) handleCursorKey(key string) (model, bool) {
+	dx, dy := 0, 0
+	switch key {
+	case "esc":
+		m.cursorOn = false
+		return m, true
+	case "z":
+		if !m.readout().IsGeographic() {
+			m.readoutProjected = !m.readoutProjected
+		}
+		return m, true
+	case "left", "h":
+		dx = -1
+	case "right", "l":
+		dx = 1
+	case "up", "k":
+		dy = -1
+	case "down", "j":
+		dy = 1
+	default:
+		return m, false
+	}
+	if m.loading || m.geometry.Width == 0 {
+		return m, true
+	}
+	cx, cy := m.geoToCell(m.geometry, m.cursorLon, m.cursorLat)
+	cx = clampInt(cx+dx, 0, m.mapWidth-1)
+	cy = clampInt(cy+dy, 0, m.mapHeight-1)
+	m.cursorLon, m.cursorLat = m.cellToGeo(m.geometry, cx, cy)
+	return m.pickAtCursor(), true
+}
+
+// pickAtCursor selects the topmost feature containing the crosshair, or the
+// nearest one when none does.
+func (m model) pickAtCursor() model {
+	// Allow one cell of slack so thin lines and points can be hit.
+	sx, sy := m.cellScale(m.geometry)
+	tolerance := m.geometry.CellSize() * maxFloat(sx, sy)
+	m.selected, m.hasSelected = m.geoData.FeatureNear(m.cursorLon, m.cursorLat, tolerance)
+	return m
+}
+
+// overlayCursor draws the crosshair glyph over canvas cell (cx, cy).
+func overlayCursor(canvas string, cx, cy int) string {
+	lines := strings.Split(canvas, "\n")
+	if cy < 0 || cy >= len(lines) {
+		return canvas
+	}
+	row := []rune(lines[cy])
+	if cx < 0 || cx >= len(row) {
+		return canvas
+	}
+	lines[cy] = string(row[:cx]) + cursorStyle.Render("+") + string(row[cx+1:])
+	return strings.Join(lines, "\n")
+}
+
+// withIdentifyPanel places the identify panel beside the map block when the
+// terminal is wide enough, otherwise below it.
+func (m model) withIdentifyPanel(mapBlock string) string {
+	room := m.width - lipgloss.Width(mapBlock) - panelStyle.GetHorizontalFrameSize()
+	if room >= minPanelWidth {
+		panel := m.identifyPanel(minInt(room, maxPanelWidth), m.mapHeight)
+		return lipgloss.JoinHorizontal(lipgloss.Top, mapBlock, panel)
+	}
+	panel := m.identifyPanel(maxInt(m.width-panelStyle.GetHorizontalFrameSize(), minPanelWidth), m.mapHeight)
+	return lipgloss.JoinVertical(lipgloss.Left, mapBlock, panel)
+}
+
+// identifyPanel lists the cursor position and every property of the picked feature.
+func (m model) identifyPanel(width, maxLines int) string {
+	lines := m.cursorReadout()
+	if !m.hasSelected || m.selected >= len(m.geoData.Features) {
+		lines = append(lines, "(no feature)")
+	} else {
+		feature := m.geoData.Features[m.selected]
+		lines = append(lines, fmt.Sprintf("Feature #%d: %s", m.selected, feature.Name), "")
+		lines = append(lines, formatProperties(feature.Properties)...)
+	}
+	if len(lines) > maxLines && maxLines > 1 {
+		hidden := len(lines) - (maxLines - 1)
+		lines = append(lines[:maxLines-1], fmt.Sprintf("... (%d more lines)", hidden))
+	}
+	return panelStyle.Width(width + panelStyle.GetHorizontalPadding()).Render(strings.Join(lines, "\n"))
+}
+
+// readout returns the projected CRS for cursor readouts: readoutCRS when set,
+// else the CRS the layer was read in.
+func (m model) readout() geo.CRS {
+	if !m.readoutCRS.IsGeographic() {
+		return m.readoutCRS
+	}
+	return m.layerCRS
+}
+
+// cursorReadout formats the cursor position as lon/lat, or as easting/northing
+// in the readout CRS when that readout is selected.
+func (m model) cursorReadout() []string {
+	if readout := m.readout(); m.readoutProjected && !readout.IsGeographic() {
+		x, y := readout.Projection.Forward(m.cursorLon, m.cursorLat)
+		return []string{
+			fmt.Sprintf("Cursor: E %.2f, N %.2f m", x, y),
+			fmt.Sprintf("  (%s)", readout.Name),
+		}
+	}
+	// Layers crossing the antimeridian are drawn with longitudes past ±180.
+	return []string{fmt.Sprintf("Cursor: %.5f, %.5f", geo.NormalizeLon(m.cursorLon), m.cursorLat)}
+}
+
+// formatProperties renders properties as "key: value" lines sorted by key.
+// Objects and arrays are pretty-printed as indented JSON below their key.
+func formatProperties(properties map[string]interface{}) []string {
+	if len(properties) == 0 {
+		return []string{"(no properties)"}
+	}
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		label := panelKeyStyle.Render(key + ":")
+		switch value := properties[key].(type) {
+		case map[string]interface{}, []interface{}:
+			b, err := json.MarshalIndent(value, "  ", "  ")
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("%s %v", label, value))
+				continue
+			}
+			lines = append(lines, label)
+			lines = append(lines, strings.Split("  "+string(b), "\n")...)
+		case nil:
+			lines = append(lines, label+" null")
+		case string:
+			lines = append(lines, label+" "+value)
+		default:
+			lines = append(lines, fmt.Sprintf("%s %v", label, value))
+		}
+	}
+	return lines
+}
@@ -2,10 +2,12 @@ package tui
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"strings"
 
 	"asciigis/internal/geo"
+	"asciigis/internal/raster"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -40,21 +42,69 @@ var (
 const (
 	minMapWidth  = 20
 	minMapHeight = 10
+
+	// zoomStep is the span factor applied by one zoom-in step.
+	zoomStep = 0.5
+	// panStep is the fraction of the viewport span moved by one pan step.
+	panStep = 0.1
 )
 
+const appTitle = "asciigis viewer"
+
 type geometryLoadedMsg struct {
+	seq      int
 	path     string
 	data     geo.Layer
 	geometry geo.TuiGeometry
+	crs      geo.CRS
 	err      error
 }
 
+// loadProgress is how far a file read has got; total is the file size in bytes
+// (0 when unknown).
+type loadProgress struct {
+	geo.Progress
+	total int64
+}
+
+// loadProgressMsg carries a progress update of load seq and the channel the
+// next update arrives on.
+type loadProgressMsg struct {
+	seq      int
+	progress loadProgress
+	updates  <-chan loadProgress
+}
+
 // Options configures the TUI behavior.
 // MapWidth/MapHeight, when > 0, request a fixed canvas size.
 // The final size may be clamped to the current terminal size.
+// DirectionGlyphs draws segments with '-', '|', '/' and '\' instead of a fixed glyph.
+// Braille renders 2x4 dots per cell using Unicode Braille characters.
+// Fill shades polygon interiors with FillGlyphs (cycled per feature) using FillRule.
+// Elevation draws lines and points with ElevationGlyphs picked by their Z value
+// (lowest to highest over the layer's elevation range); it has no effect in Braille mode.
+// Projection names the display projection (see geo.ProjectionNames).
+// SourceCRS is the coordinate system of the input file; projected data is
+// reprojected to lon/lat on load. The zero value uses the file's legacy "crs"
+// member, or WGS84 when there is none. ReadoutCRS is the alternative system for
+// cursor readouts (defaults to the source CRS).
+// CellAspect is the height/width ratio of a terminal cell used to keep the map's
+// aspect ratio; 0 selects geo.DefaultCellAspect and a negative value stretches
+// the map to fill the canvas.
 type Options struct {
-	MapWidth  int
-	MapHeight int
+	MapWidth        int
+	MapHeight       int
+	DirectionGlyphs bool
+	Braille         bool
+	Fill            bool
+	FillGlyphs      []rune
+	FillRule        raster.FillRule
+	Elevation       bool
+	ElevationGlyphs []rune
+	Projection      string
+	SourceCRS       geo.CRS
+	ReadoutCRS      geo.CRS
+	CellAspect      float64
 }
 
 type model struct {
@@ -63,6 +113,7 @@ type model struct {
 	geoData        geo.Layer
 	editing        bool
 	geometry       geo.TuiGeometry
+	view           geo.Bound
 	width          int
 	height         int
 	mapWidth       int
@@ -71,14 +122,69 @@ type model struct {
 	maxMapHeight   int
 	fixedMapWidth  int
 	fixedMapHeight int
+	style          raster.Style
+	projection     string
+	sourceCRS      geo.CRS
+	layerCRS       geo.CRS // CRS the loaded file was read in (sourceCRS or its "crs" member)
+	readoutCRS     geo.CRS
+	cellAspect     float64
 	ready          bool
 	loading        bool
+	loadSeq        int
+	progress       loadProgress
 	err            error
+
+	// Mouse drag state: the press position and the projection at that time.
+	dragging  bool
+	dragMoved bool
+	dragX     int
+	dragY     int
+	dragFrom  geo.TuiGeometry
+
+	// Identify crosshair, kept in lon/lat so it survives pan and zoom.
+	cursorOn  bool
+	cursorLon float64
+	cursorLat float64
+	// readoutProjected shows the cursor in readoutCRS instead of lon/lat.
+	readoutProjected bool
+	selected         int
+	hasSelected      bool
+
+	// Diagnostics panel and the index of its first visible entry.
+	diagnosticsOn     bool
+	diagnosticsOffset int
 }
 
-// NewModel creates a Bubble Tea model configured with a GeoJSON path.
+// NewModel creates a Bubble Tea model configured with a data file path.
 func NewModel(geoPath string, opts Options) model {
-	m := model{geoPath: geoPath, fixedMapWidth: opts.MapWidth, fixedMapHeight: opts.MapHeight}
+	m := model{
+		geoPath:        geoPath,
+		fixedMapWidth:  opts.MapWidth,
+		fixedMapHeight: opts.MapHeight,
+		style: raster.Style{
+			DirectionGlyphs: opts.DirectionGlyphs,
+			Braille:         opts.Braille,
+			Fill:            opts.Fill,
+			FillGlyphs:      opts.FillGlyphs,
+			FillRule:        opts.FillRule,
+			Elevation:       opts.Elevation,
+			ElevationGlyphs: opts.ElevationGlyphs,
+		},
+		projection: opts.Projection,
+		sourceCRS:  opts.SourceCRS,
+		readoutCRS: opts.ReadoutCRS,
+		cellAspect: opts.CellAspect,
+	}
+	m.layerCRS = m.sourceCRS
+	if m.layerCRS.Name == "" {
+		m.layerCRS = geo.CRSWGS84
+	}
+	if m.projection == "" {
+		m.projection = geo.ProjectionNames[0]
+	}
+	if m.cellAspect == 0 {
+		m.cellAspect = geo.DefaultCellAspect
+	}
 	if strings.TrimSpace(geoPath) == "" {
 		m.editing = true
 		m.inputPath = ""
@@ -95,7 +201,7 @@ func Run(geoPath string) error {
 
 // RunWithOptions launches the TUI with additional configuration.
 func RunWithOptions(geoPath string, opts Options) error {
-	_, err := tea.NewProgram(NewModel(geoPath, opts), tea.WithAltScreen()).Run()
+	_, err := tea.NewProgram(NewModel(geoPath, opts), tea.WithAltScreen(), tea.WithMouseCellMotion()).Run()
 	return err
 }
 
@@ -128,12 +234,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.ready = true
 		if strings.TrimSpace(m.geoPath) != "" && (prevW != m.mapWidth || prevH != m.mapHeight || m.geometry.Width == 0 || m.geometry.Height == 0) {
 			m.loading = true
-			return m, loadGeometryCmd(m.geoPath, m.geoData, m.mapWidth, m.mapHeight)
+			return m, m.loadCmd(m.geoData)
 		}
 		m.loading = false
 		return m, nil
 
+	case loadProgressMsg:
+		if msg.seq != m.loadSeq || !m.loading {
+			return m, nil
+		}
+		m.progress = msg.progress
+		return m, waitForProgress(msg.seq, msg.updates)
+
 	case geometryLoadedMsg:
+		if msg.seq != m.loadSeq {
+			// Ignore results superseded by a newer load.
+			return m, nil
+		}
 		m.loading = false
 		if msg.err != nil {
 			m.err = msg.err
@@ -145,10 +262,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.geoData = msg.data
+		m.layerCRS = msg.crs
 		m.geometry = msg.geometry
+		m.view = msg.geometry.Bounds
 		m.err = nil
+		m.diagnosticsOn = m.diagnosticsOn && len(m.geoData.Diagnostics) > 0
+		m = m.scrollDiagnostics(0)
+		if m.cursorOn {
+			m = m.pickAtCursor()
+		}
 		return m, nil
 
+	case tea.MouseMsg:
+		if m.editing {
+			return m, nil
+		}
+		return m.handleMouse(msg)
+
 	case tea.KeyMsg:
 		// Path input mode.
 		if m.editing {
@@ -173,12 +303,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.geoPath = p
 				m.inputPath = p
 				m.geoData = cacheInvalid
+				m.hasSelected = false
+				m.cursorOn = false
 				m.editing = false
 				m.loading = true
 				m.err = nil
 				m.geometry = geo.TuiGeometry{}
 				if m.ready {
-					return m, loadGeometryCmd(m.geoPath, cacheInvalid, m.mapWidth, m.mapHeight)
+					return m, m.loadCmd(cacheInvalid)
 				}
 				return m, nil
 			case "backspace", "ctrl+h":
@@ -196,6 +328,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.diagnosticsOn {
+			if next, ok := m.handleDiagnosticsKey(msg.String()); ok {
+				return next, nil
+			}
+		}
+		if m.cursorOn {
+			if next, ok := m.handleCursorKey(msg.String()); ok {
+				return next, nil
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
@@ -203,12 +346,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.ready && strings.TrimSpace(m.geoPath) != "" {
 				m.loading = true
 				m.geoData = cacheInvalid // Clear cached data to force reload.
-				return m, loadGeometryCmd(m.geoPath, cacheInvalid, m.mapWidth, m.mapHeight)
+				m.hasSelected = false
+				m.cursorOn = false
+				m.diagnosticsOn = false
+				return m, m.loadCmd(cacheInvalid)
 			}
 		case "c":
 			m.geoPath = ""
 			m.inputPath = ""
 			m.geoData = cacheInvalid
+			m.hasSelected = false
+			m.cursorOn = false
+			m.diagnosticsOn = false
 			m.geometry = geo.TuiGeometry{}
 			m.err = nil
 			m.loading = false
@@ -222,6 +371,50 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.resizeCanvas(0, 1)
 		case "s":
 			return m.resizeCanvas(0, -1)
+		case "g":
+			m.style.DirectionGlyphs = !m.style.DirectionGlyphs
+			return m, nil
+		case "f":
+			m.style.Fill = !m.style.Fill
+			return m, nil
+		case "v":
+			m.style.Elevation = !m.style.Elevation
+			return m, nil
+		case "b":
+			m.style.Braille = !m.style.Braille
+			if m.ready && strings.TrimSpace(m.geoPath) != "" {
+				// The projection grid changes size, so reproject from the cached layer.
+				// The current geometry no longer matches the grid, so drop it meanwhile.
+				m.loading = true
+				m.geometry = geo.TuiGeometry{}
+				return m, m.loadCmd(m.geoData)
+			}
+			return m, nil
+		case "m":
+			m.projection = nextProjection(m.projection)
+			if m.ready && m.geoData.Valid {
+				m.loading = true
+				return m, m.loadCmd(m.geoData)
+			}
+			return m, nil
+		case "+", "=":
+			return m.setView(m.view.Zoom(zoomStep))
+		case "-", "_":
+			return m.setView(m.view.Zoom(1 / zoomStep))
+		case "left", "h":
+			return m.setView(m.view.Pan(-panStep, 0))
+		case "right", "l":
+			return m.setView(m.view.Pan(panStep, 0))
+		case "up", "k":
+			return m.setView(m.view.Pan(0, panStep))
+		case "down", "j":
+			return m.setView(m.view.Pan(0, -panStep))
+		case "0":
+			return m.setView(m.geoData.Extent())
+		case "x":
+			return m.toggleCursor(), nil
+		case "e":
+			return m.toggleDiagnostics(), nil
 		case "/", "p":
 			m.editing = true
 			if strings.TrimSpace(m.geoPath) != "" {
@@ -238,7 +431,13 @@ func (m model) View() string {
 		return "Calculating viewport..."
 	}
 
-	mapBlock := mapStyle.Width(m.mapWidth).Render(renderCanvas(m.geometry, m.loading, m.err))
+	// lipgloss counts padding in Width, so widen the block to keep canvas rows unwrapped.
+	canvas := renderCanvas(m.geometry, m.style, m.reading(), m.progress, m.err)
+	if m.cursorOn && !m.reading() && m.err == nil && m.geometry.Width > 0 {
+		cx, cy := m.geoToCell(m.geometry, m.cursorLon, m.cursorLat)
+		canvas = overlayCursor(canvas, cx, cy)
+	}
+	mapBlock := mapStyle.Width(m.mapWidth + mapStyle.GetHorizontalPadding()).Render(canvas)
 
 	pathPanel := ""
 	if m.editing {
@@ -246,7 +445,7 @@ func (m model) View() string {
 		// simple caret
 		input = input + "_"
 		pathPanel = infoStyle.Render(strings.Join([]string{
-			"Enter GeoJSON path:",
+			"Enter file path (GeoJSON, shapefile .shp/.zip, KML/KMZ, GPX or WKT):",
 			input,
 			"Enter: load | Esc: cancel | Ctrl+U: clear",
 		}, "\n"))
@@ -255,10 +454,22 @@ func (m model) View() string {
 	infoLines := []string{fmt.Sprintf("File: %s", emptyWhen(strings.TrimSpace(m.geoPath), "(none)"))}
 	if m.geometry.Width > 0 && m.geometry.Height > 0 && m.err == nil {
 		infoLines = append(infoLines,
-			fmt.Sprintf("Bounds: lon %.4f .. %.4f | lat %.4f .. %.4f", m.geometry.Bounds.LonMin, m.geometry.Bounds.LonMax, m.geometry.Bounds.LatMin, m.geometry.Bounds.LatMax),
-			fmt.Sprintf("Canvas: %dx%d", m.geometry.Width, m.geometry.Height),
+			fmt.Sprintf("Bounds: %s", formatBound(m.geoData.Bounds)),
+			fmt.Sprintf("View: %s", formatBound(m.geometry.Transform.VisibleBounds())),
+			canvasInfo(m.geometry, m.style),
+			fmt.Sprintf("Projection: %s", m.projection),
+			fmt.Sprintf("Source CRS: %s", sourceCRSInfo(m.layerCRS, m.sourceCRS)),
 			fmt.Sprintf("Polygons: %d", len(m.geometry.Polygons)),
 		)
+		if elevation := m.geoData.Elevation; elevation != nil {
+			infoLines = append(infoLines, elevationInfo(*elevation, m.style))
+		}
+		if m.geoData.Skipped > 0 {
+			infoLines = append(infoLines, fmt.Sprintf("Skipped features: %d", m.geoData.Skipped))
+		}
+		if diags := m.geoData.Diagnostics; len(diags) > 0 {
+			infoLines = append(infoLines, fmt.Sprintf("Diagnostics: %s (e: show)", diags.Summary()))
+		}
 	}
 	if m.err != nil {
 		infoLines = append(infoLines, fmt.Sprintf("Error: %v", m.err))
@@ -270,20 +481,38 @@ func (m model) View() string {
 		statusText = "Loading..."
 	}
 
-	footerText := fmt.Sprintf("q: quit | r: reload | c: clear | a/d: width -/+ | w/s: height +/- | / or p: set path | %s", statusText)
+	footerText := fmt.Sprintf("q: quit | r: reload | c: clear | a/d: width -/+ | w/s: height +/- | g: glyphs | f: fill | v: elevation | b: braille | m: projection | hjkl/arrows: pan | +/-: zoom | 0: full extent | x: identify | e: diagnostics | mouse: wheel zoom, drag pan, click identify | / or p: set path | %s", statusText)
+	if m.cursorOn {
+		readoutHelp := ""
+		if readout := m.readout(); !readout.IsGeographic() {
+			readoutHelp = "z: lon/lat <-> " + readout.Name + " | "
+		}
+		footerText = fmt.Sprintf("identify: hjkl/arrows: move cursor | +/-: zoom | %sx or esc: close | q: quit | %s", readoutHelp, statusText)
+	}
+	if m.diagnosticsOn {
+		footerText = fmt.Sprintf("diagnostics: j/k or arrows: scroll | pgup/pgdn: page | home/end | e or esc: close | q: quit | %s", statusText)
+	}
 	if m.editing {
 		footerText = "q: quit | typing..."
 	}
 	footer := helpStyle.Render(footerText)
 
+	if m.cursorOn {
+		mapBlock = m.withIdentifyPanel(mapBlock)
+	}
+
 	parts := []string{
-		titleStyle.Render("asciigis viewer"),
+		titleStyle.Render(appTitle),
 		mapBlock,
 	}
 	if m.editing {
 		parts = append(parts, pathPanel)
 	}
-	parts = append(parts, info, footer)
+	parts = append(parts, info)
+	if m.diagnosticsOn {
+		parts = append(parts, m.diagnosticsPanel(maxInt(m.width-diagnosticsStyle.GetHorizontalFrameSize(), minPanelWidth)))
+	}
+	parts = append(parts, footer)
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -291,9 +520,17 @@ func (m model) View() string {
 	)
 }
 
-func renderCanvas(geometry geo.TuiGeometry, loading bool, loadErr error) string {
+// reading reports whether the canvas should show the load progress instead of
+// the map: while a file is read, or before there is any geometry to draw.
+// Reprojecting the cached layer for a new view keeps the current map on screen
+// until the new geometry arrives.
+func (m model) reading() bool {
+	return m.loading && (!m.geoData.Valid || m.geometry.Width == 0 || m.geometry.Height == 0)
+}
+
+func renderCanvas(geometry geo.TuiGeometry, style raster.Style, loading bool, progress loadProgress, loadErr error) string {
 	if loading {
-		return "Loading..."
+		return formatProgress(progress)
 	}
 	if loadErr != nil {
 		return fmt.Sprintf("Failed to load: %v", loadErr)
@@ -302,61 +539,334 @@ func renderCanvas(geometry geo.TuiGeometry, loading bool, loadErr error) string
 		return "No geometry yet (press '/' to set path)"
 	}
 
-	canvas := make([][]rune, geometry.Height)
-	for y := 0; y < geometry.Height; y++ {
-		row := make([]rune, geometry.Width)
-		for x := 0; x < geometry.Width; x++ {
-			row[x] = ' '
+	return raster.Render(geometry, style)
+}
+
+// loadCmd (re)projects the current path onto the canvas grid for the active style.
+// The current viewport is kept unless the layer has to be (re)read; reads from
+// disk report their progress until the layer is loaded.
+// Each call supersedes results of earlier loads that are still in flight.
+func (m *model) loadCmd(cached geo.Layer) tea.Cmd {
+	m.loadSeq++
+	m.progress = loadProgress{}
+	gridW, gridH := m.style.GridSize(m.mapWidth, m.mapHeight)
+	req := loadRequest{
+		seq:        m.loadSeq,
+		path:       m.geoPath,
+		view:       m.view,
+		projection: m.projection,
+		sourceCRS:  m.sourceCRS,
+		layerCRS:   m.layerCRS,
+		width:      gridW,
+		height:     gridH,
+		aspect:     m.style.GridAspect(m.cellAspect),
+	}
+	if cached.Valid {
+		return loadGeometryCmd(req, cached)
+	}
+	updates := make(chan loadProgress, 1)
+	req.progress = updates
+	return tea.Batch(loadGeometryCmd(req, cached), waitForProgress(m.loadSeq, updates))
+}
+
+// waitForProgress delivers the next progress update of load seq. It returns no
+// message once the load has finished and closed updates.
+func waitForProgress(seq int, updates <-chan loadProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-updates
+		if !ok {
+			return nil
 		}
-		canvas[y] = row
+		return loadProgressMsg{seq: seq, progress: progress, updates: updates}
 	}
+}
 
-	for _, polygon := range geometry.Polygons {
-		for _, ring := range polygon.Rings {
-			for _, coord := range ring {
-				x, y := coord[0], coord[1]
-				if x < 0 || y < 0 || x >= geometry.Width || y >= geometry.Height {
-					continue
-				}
-				canvas[y][x] = '*'
-			}
+// formatProgress describes a load in progress for the canvas placeholder.
+func formatProgress(progress loadProgress) string {
+	switch {
+	case progress.total > 0:
+		percent := float64(progress.Bytes) / float64(progress.total) * 100
+		return fmt.Sprintf("Loading... %.0f%% (%d features)", math.Min(percent, 100), progress.Features)
+	case progress.Features > 0:
+		return fmt.Sprintf("Loading... %d features", progress.Features)
+	}
+	return "Loading..."
+}
+
+// setView moves the viewport and reprojects the cached layer against it.
+func (m model) setView(view geo.Bound) (tea.Model, tea.Cmd) {
+	if !m.ready || !m.geoData.Valid {
+		return m, nil
+	}
+	m.view = view
+	m.loading = true
+	return m, m.loadCmd(m.geoData)
+}
+
+// handleMouse zooms with the wheel around the pointer, pans by dragging with the
+// left button and identifies the feature under the pointer on a click.
+func (m model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown:
+		lon, lat, ok := m.mouseToGeo(m.geometry, msg.X, msg.Y)
+		if !ok {
+			return m, nil
 		}
+		factor := zoomStep
+		if msg.Button == tea.MouseButtonWheelDown {
+			factor = 1 / zoomStep
+		}
+		return m.setView(m.view.ZoomAt(lon, lat, factor))
+
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+		if _, _, ok := m.mouseToGeo(m.geometry, msg.X, msg.Y); !ok {
+			return m, nil
+		}
+		m.dragging = true
+		m.dragMoved = false
+		m.dragX, m.dragY = msg.X, msg.Y
+		m.dragFrom = m.geometry
+		return m, nil
+
+	case msg.Action == tea.MouseActionMotion && m.dragging:
+		if msg.X == m.dragX && msg.Y == m.dragY {
+			return m, nil
+		}
+		m.dragMoved = true
+		return m.dragTo(msg.X, msg.Y)
+
+	case msg.Action == tea.MouseActionRelease && m.dragging:
+		m.dragging = false
+		if m.dragMoved {
+			return m.dragTo(msg.X, msg.Y)
+		}
+		return m.identifyAt(msg.X, msg.Y), nil
+	}
+	return m, nil
+}
+
+// dragTo pans so that the point grabbed at the press follows the pointer.
+func (m model) dragTo(x, y int) (tea.Model, tea.Cmd) {
+	fromLon, fromLat := m.screenToGeo(m.dragFrom, m.dragX, m.dragY)
+	toLon, toLat := m.screenToGeo(m.dragFrom, x, y)
+	return m.setView(m.dragFrom.Bounds.Translate(fromLon-toLon, fromLat-toLat))
+}
+
+// identifyAt moves the identify cursor to the terminal position (x, y).
+func (m model) identifyAt(x, y int) model {
+	lon, lat, ok := m.mouseToGeo(m.geometry, x, y)
+	if !ok {
+		return m
 	}
+	m.cursorOn = true
+	m.cursorLon, m.cursorLat = lon, lat
+	return m.pickAtCursor()
+}
 
-	lines := make([]string, len(canvas))
-	for i, row := range canvas {
-		lines[i] = string(row)
+// mapOrigin returns the terminal position of the canvas' top-left cell.
+func (m model) mapOrigin() (int, int) {
+	top := lipgloss.Height(titleStyle.Render(appTitle))
+	return mapStyle.GetBorderLeftSize() + mapStyle.GetPaddingLeft(),
+		top + mapStyle.GetBorderTopSize() + mapStyle.GetPaddingTop()
+}
+
+// mouseToGeo converts a terminal position to lon/lat using geometry's projection.
+// ok is false when the position lies outside the canvas.
+func (m model) mouseToGeo(geometry geo.TuiGeometry, x, y int) (lon, lat float64, ok bool) {
+	if m.editing || geometry.Width == 0 || geometry.Height == 0 {
+		return 0, 0, false
+	}
+	ox, oy := m.mapOrigin()
+	if x < ox || y < oy || x >= ox+m.mapWidth || y >= oy+m.mapHeight {
+		return 0, 0, false
 	}
-	return strings.Join(lines, "\n")
+	lon, lat = m.screenToGeo(geometry, x, y)
+	return lon, lat, true
+}
+
+// screenToGeo returns lon/lat at the center of the terminal cell (x, y).
+func (m model) screenToGeo(geometry geo.TuiGeometry, x, y int) (float64, float64) {
+	ox, oy := m.mapOrigin()
+	return m.cellToGeo(geometry, x-ox, y-oy)
+}
+
+// cellScale returns how many grid units of geometry make up one canvas cell
+// (several dots per cell in Braille mode).
+func (m model) cellScale(geometry geo.TuiGeometry) (float64, float64) {
+	sx := float64(geometry.Width) / float64(maxInt(m.mapWidth, 1))
+	sy := float64(geometry.Height) / float64(maxInt(m.mapHeight, 1))
+	return sx, sy
+}
+
+// cellToGeo returns lon/lat at the center of canvas cell (cx, cy).
+func (m model) cellToGeo(geometry geo.TuiGeometry, cx, cy int) (float64, float64) {
+	sx, sy := m.cellScale(geometry)
+	return geometry.ToGeo((float64(cx)+0.5)*sx-0.5, (float64(cy)+0.5)*sy-0.5)
 }
 
-func loadGeometryCmd(path string, cached geo.Layer, width, height int) tea.Cmd {
+// geoToCell returns the canvas cell containing lon/lat.
+func (m model) geoToCell(geometry geo.TuiGeometry, lon, lat float64) (int, int) {
+	sx, sy := m.cellScale(geometry)
+	p := geometry.ToTui(lon, lat)
+	return int(float64(p[0]) / sx), int(float64(p[1]) / sy)
+}
+
+// loadRequest describes which file to load and how to project it onto the canvas grid.
+type loadRequest struct {
+	seq        int
+	path       string
+	view       geo.Bound
+	projection string
+	sourceCRS  geo.CRS
+	layerCRS   geo.CRS // CRS the cached layer was read in
+	width      int
+	height     int
+	aspect     float64
+	// progress, when set, receives read progress and is closed when the load ends.
+	progress chan loadProgress
+}
+
+// loadGeometryCmd projects the layer against req.view. When the layer is read
+// from disk, it is reprojected from req.sourceCRS (or the file's "crs" member
+// when req.sourceCRS is unset), shifted to continuous longitudes when it crosses
+// the antimeridian, and the view is replaced with the initial extent of the
+// freshly loaded data (its declared bbox, or the computed bounds).
+func loadGeometryCmd(req loadRequest, cached geo.Layer) tea.Cmd {
 	return func() tea.Msg {
+		if req.progress != nil {
+			defer close(req.progress)
+		}
+		seq, path := req.seq, req.path
 		p := strings.TrimSpace(path)
 		if p == "" {
-			return geometryLoadedMsg{path: path, err: fmt.Errorf("path is empty")}
+			return geometryLoadedMsg{seq: seq, path: path, err: fmt.Errorf("path is empty")}
 		}
 
 		data := cached
+		view := req.view
+		crs := req.layerCRS
 		if !cached.Valid {
-			b, err := os.ReadFile(p)
+			var err error
+			data, err = readLayer(p, req.progress)
 			if err != nil {
-				return geometryLoadedMsg{path: path, err: fmt.Errorf("read file: %w", err)}
+				return geometryLoadedMsg{seq: seq, path: path, err: err}
 			}
-			data, err = geo.BytesToLayer(b)
+			crs, data.Diagnostics = resolveSourceCRS(req.sourceCRS, data)
+			data, err = geo.ReprojectLayer(data, crs)
 			if err != nil {
-				return geometryLoadedMsg{path: path, err: fmt.Errorf("parse JSON: %w", err)}
+				return geometryLoadedMsg{seq: seq, path: path, err: fmt.Errorf("reproject from %s: %w", crs.Name, err)}
 			}
+			data = geo.WrapAntimeridian(data)
+			view = data.Extent()
 		}
 
-		geometry, err := geo.ConvertTuiBytes(data, width, height)
+		proj, err := geo.NewProjection(req.projection, data.Bounds)
+		if err != nil {
+			return geometryLoadedMsg{seq: seq, path: path, data: data, crs: crs, err: err}
+		}
+		geometry, err := geo.ConvertTuiBytes(data, view, proj, req.width, req.height, req.aspect)
 		if err != nil {
-			return geometryLoadedMsg{path: path, data: data, err: fmt.Errorf("convert geometry: %w", err)}
+			return geometryLoadedMsg{seq: seq, path: path, data: data, crs: crs, err: fmt.Errorf("convert geometry: %w", err)}
 		}
-		return geometryLoadedMsg{path: path, data: data, geometry: geometry, err: nil}
+		return geometryLoadedMsg{seq: seq, path: path, data: data, geometry: geometry, crs: crs, err: nil}
 	}
 }
 
+// readLayer reads the file at path into a layer in the format its extension
+// names (see geo.ReadFile), sending progress updates to progress (when set)
+// without blocking the read.
+func readLayer(path string, progress chan<- loadProgress) (geo.Layer, error) {
+	var report func(geo.Progress)
+	if progress != nil {
+		var total int64
+		if info, err := os.Stat(path); err == nil {
+			total = info.Size()
+		}
+		report = func(p geo.Progress) {
+			select {
+			case progress <- loadProgress{Progress: p, total: total}:
+			default: // The UI has not taken the previous update yet; skip this one.
+			}
+		}
+	}
+	return geo.ReadFile(path, report)
+}
+
+// resolveSourceCRS picks the CRS a freshly read layer is reprojected from: the
+// explicit one when set, else the layer's "crs" member, else WGS84. An
+// unsupported "crs" member is added to the diagnostics as a warning and read as WGS84.
+func resolveSourceCRS(explicit geo.CRS, layer geo.Layer) (geo.CRS, geo.Diagnostics) {
+	diags := layer.Diagnostics
+	if explicit.Name != "" {
+		return explicit, diags
+	}
+	if layer.CRSName == "" {
+		return geo.CRSWGS84, diags
+	}
+	crs, err := geo.ParseCRS(layer.CRSName)
+	if err != nil {
+		diags = append(diags[:len(diags):len(diags)], geo.Diagnostic{
+			Severity: geo.SeverityWarning,
+			Pointer:  "/crs",
+			Message:  fmt.Sprintf("ignoring crs member: %v; reading as WGS84", err),
+		})
+		return geo.CRSWGS84, diags
+	}
+	return crs, diags
+}
+
+// sourceCRSInfo names the CRS the layer was read in, noting when it came from the file.
+func sourceCRSInfo(layerCRS, explicit geo.CRS) string {
+	if explicit.Name == "" && !layerCRS.IsGeographic() {
+		return layerCRS.Name + " (from file)"
+	}
+	return layerCRS.Name
+}
+
+// nextProjection returns the projection after name in geo.ProjectionNames.
+func nextProjection(name string) string {
+	for i, candidate := range geo.ProjectionNames {
+		if candidate == name {
+			return geo.ProjectionNames[(i+1)%len(geo.ProjectionNames)]
+		}
+	}
+	return geo.ProjectionNames[0]
+}
+
+// formatBound prints a bound that crosses the antimeridian with its longitudes
+// normalized, so the west edge reads larger than the east edge.
+func formatBound(b geo.Bound) string {
+	if b.CrossesAntimeridian() {
+		return fmt.Sprintf("lon %.4f .. %.4f (crosses 180°) | lat %.4f .. %.4f",
+			geo.NormalizeLon(b.LonMin), geo.NormalizeLon(b.LonMax), b.LatMin, b.LatMax)
+	}
+	return fmt.Sprintf("lon %.4f .. %.4f | lat %.4f .. %.4f", b.LonMin, b.LonMax, b.LatMin, b.LatMax)
+}
+
+func canvasInfo(geometry geo.TuiGeometry, style raster.Style) string {
+	if style.Braille {
+		return fmt.Sprintf("Canvas: %dx%d (braille %dx%d dots)",
+			geometry.Width/raster.BrailleDotsX, geometry.Height/raster.BrailleDotsY, geometry.Width, geometry.Height)
+	}
+	return fmt.Sprintf("Canvas: %dx%d", geometry.Width, geometry.Height)
+}
+
+// elevationInfo describes the layer's Z range and, when shading is on, the glyph ramp.
+func elevationInfo(r geo.Range, style raster.Style) string {
+	text := fmt.Sprintf("Elevation: %.2f .. %.2f", r.Min, r.Max)
+	switch {
+	case !style.Elevation:
+		return text + " (v: shade)"
+	case style.Braille:
+		return text + " (shading needs glyph mode)"
+	}
+	glyphs := style.ElevationGlyphs
+	if len(glyphs) == 0 {
+		glyphs = raster.DefaultElevationGlyphs
+	}
+	return fmt.Sprintf("%s (low %s high)", text, string(glyphs))
+}
+
 func dropLastRune(s string) string {
 	if s == "" {
 		return s
@@ -389,6 +899,13 @@ func minInt(a, b int) int {
 	return b
 }
 
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func clampInt(v, lo, hi int) int {
 	if v < lo {
 		return lo
@@ -431,5 +948,5 @@ func (m model) resizeCanvas(deltaW, deltaH int) (tea.Model, tea.Cmd) {
 	m.loading = true
 	m.err = nil
 	m.geometry = geo.TuiGeometry{}
-	return m, loadGeometryCmd(m.geoPath, m.geoData, m.mapWidth, m.mapHeight)
+	return m, m.loadCmd(m.geoData)
 }